@@ -0,0 +1,170 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+// Version is the version of breeze-generator. code cached by other versions will be regenerated.
+const Version = "0.2.0"
+
+//Cache keeps generated code of each schema and template, keyed by the content hash of the schema,
+//the hashes of the schemas it depends on directly or indirectly, the options, the generator version,
+//and the files which the template depends on if it is a core.CacheKeyTemplate.
+//a schema will be regenerated by a template only when one of them changes.
+type Cache struct {
+	Path    string // file to persist the cache, the cache is only kept in memory if Path is empty
+	entries map[string]*cacheEntry
+	used    map[string]bool
+	touched map[string]bool // templates used since last saving
+	hits    int
+	misses  int
+	lock    sync.Mutex
+}
+
+type cacheEntry struct {
+	Key   string            `json:"key"`
+	Files map[string]string `json:"files"`
+}
+
+type cacheFile struct {
+	Version string                 `json:"version"`
+	Entries map[string]*cacheEntry `json:"entries"`
+}
+
+//NewCache create a Cache, and load the cache file if path is not empty and the file exists
+func NewCache(path string) (*Cache, error) {
+	c := &Cache{Path: path, entries: make(map[string]*cacheEntry), used: make(map[string]bool), touched: make(map[string]bool)}
+	if path == "" {
+		return c, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+	f := &cacheFile{}
+	if err = json.Unmarshal(content, f); err != nil {
+		return nil, fmt.Errorf("wrong cache file: %s, err:%s", path, err.Error())
+	}
+	if f.Version == Version && f.Entries != nil {
+		c.entries = f.Entries
+	}
+	return c, nil
+}
+
+//Invalidate removes all cached code, so everything will be regenerated next time
+func (c *Cache) Invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+	c.used = make(map[string]bool)
+	c.touched = make(map[string]bool)
+}
+
+//Stats returns the count of cache hits and misses since the cache created
+func (c *Cache) Stats() (hits int, misses int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.hits, c.misses
+}
+
+//Save writes the cache into Path. entries not used since last saving are removed,
+//unless their template is not used, so a cache can be shared by generations of different templates.
+func (c *Cache) Save() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for name := range c.entries {
+		if !c.used[name] && c.touched[cachedTemplate(name)] {
+			delete(c.entries, name)
+		}
+	}
+	c.used = make(map[string]bool)
+	c.touched = make(map[string]bool)
+	if c.Path == "" {
+		return nil
+	}
+	content, err := json.Marshal(&cacheFile{Version: Version, Entries: c.entries})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.Path, content, 0666)
+}
+
+func (c *Cache) get(name string, key string) map[string][]byte {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.used[name] = true
+	c.touched[cachedTemplate(name)] = true
+	entry := c.entries[name]
+	if entry == nil || entry.Key != key {
+		c.misses++
+		return nil
+	}
+	c.hits++
+	files := make(map[string][]byte, len(entry.Files))
+	for fileName, content := range entry.Files {
+		files[fileName] = []byte(content)
+	}
+	return files
+}
+
+func (c *Cache) put(name string, key string, files map[string][]byte) {
+	entry := &cacheEntry{Key: key, Files: make(map[string]string, len(files))}
+	for fileName, content := range files {
+		entry.Files[fileName] = string(content)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.used[name] = true
+	c.entries[name] = entry
+}
+
+// cachedTemplate returns the template name of a cache entry name "template:schema"
+func cachedTemplate(name string) string {
+	if index := strings.Index(name, ":"); index >= 0 {
+		return name[:index]
+	}
+	return name
+}
+
+// cacheKeys builds the cache key of each schema generated by template with context.
+func cacheKeys(context *core.Context, template core.CodeTemplate) map[string]string {
+	deps, missing := dependencyClosure(dependencies(context))
+	var templateKey string
+	if t, ok := template.(core.CacheKeyTemplate); ok {
+		templateKey = t.CacheKey(context)
+	}
+	optionKeys := make([]string, 0, len(context.Options))
+	for k := range context.Options {
+		optionKeys = append(optionKeys, k)
+	}
+	sort.Strings(optionKeys)
+	keys := make(map[string]string, len(context.Schemas))
+	for _, schema := range context.SortedSchemas() {
+		h := sha256.New()
+		fmt.Fprintf(h, "version:%s\nschema:%s:%s\nuniform:%s\ntemplate:%s:%s\n", Version, schema.Name, schema.Hash, context.UniformPackage, template.Name(), templateKey)
+		for _, k := range optionKeys {
+			fmt.Fprintf(h, "option:%s=%s\n", k, context.Options[k])
+		}
+		for _, dep := range deps[schema.Name] {
+			fmt.Fprintf(h, "dependency:%s:%s\n", dep, context.GetSchema(dep).Hash)
+		}
+		for _, name := range missing[schema.Name] {
+			fmt.Fprintf(h, "missing:%s\n", name)
+		}
+		keys[schema.Name] = hex.EncodeToString(h.Sum(nil))
+	}
+	return keys
+}
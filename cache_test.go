@@ -0,0 +1,58 @@
+package generator
+
+import (
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	assert := assert2.New(t)
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.breeze": "package test.a;\nmessage A {\n    test.b.B b = 1;\n}\n",
+		"b.breeze": "package test.b;\nmessage B {\n    int32 id = 1;\n}\n",
+		"c.breeze": "package test.c;\nmessage C {\n    int32 id = 1;\n}\n",
+	}
+	cache, err := NewCache(dir + "/cache.json")
+	assert.Nil(err)
+	config := &Config{CodeTemplates: "java,go", Cache: cache}
+	code, _, err := GeneratByFileContent(files, config)
+	assert.Nil(err)
+	hits, misses := cache.Stats()
+	assert.Equal(0, hits)
+	assert.Equal(6, misses)
+
+	// load from cache file, nothing changed
+	cache, err = NewCache(dir + "/cache.json")
+	assert.Nil(err)
+	config = &Config{CodeTemplates: "java,go", Cache: cache}
+	cachedCode, _, err := GeneratByFileContent(files, config)
+	assert.Nil(err)
+	assert.Equal(code, cachedCode)
+	hits, misses = cache.Stats()
+	assert.Equal(6, hits)
+	assert.Equal(0, misses)
+
+	// a dependency changed, the schema depends on it will be regenerated
+	files["b.breeze"] = "package test.b;\nenum B {\n    X = 1;\n}\n"
+	_, _, err = GeneratByFileContent(files, &Config{CodeTemplates: "java,go", Cache: cache})
+	assert.Nil(err)
+	hits, misses = cache.Stats()
+	assert.Equal(8, hits)
+	assert.Equal(4, misses)
+
+	// options changed, only the template accepting the option is regenerated
+	_, _, err = GeneratByFileContent(files, &Config{CodeTemplates: "java,go", Cache: cache, Options: map[string]string{"java_package": "x"}})
+	assert.Nil(err)
+	hits, misses = cache.Stats()
+	assert.Equal(11, hits)
+	assert.Equal(7, misses)
+
+	cache.Invalidate()
+	_, _, err = GeneratByFileContent(files, &Config{CodeTemplates: "java,go", Cache: cache, Options: map[string]string{"java_package": "x"}})
+	assert.Nil(err)
+	hits, misses = cache.Stats()
+	assert.Equal(11, hits)
+	assert.Equal(13, misses)
+}
@@ -2,7 +2,9 @@ package core
 
 import (
 	"errors"
+	"sort"
 	"strings"
+	"sync"
 )
 
 //breeze type for generate code.
@@ -25,7 +27,18 @@ const (
 const (
 	JavaPackage     = "java_package"
 	GoPackagePrefix = "go_package_prefix"
+	GoPackage       = "go_package" // go import path of schema, and optional package name after `;` or blank
 	WithPackageDir  = "with_package_dir"
+	WithDescriptor  = "with_descriptor"   // embed binary descriptor set of schema into generated code
+	WithTests       = "with_tests"        // generate tests which encode and decode messages with pseudo-random values
+	JavaTestDir     = "java_test_dir"     // directory of generated java tests relative to the java output directory, such as `../../test/java`
+	KeepUnknownEnum = "keep_unknown_enum" // keep unknown enum numbers instead of failing to decode
+	GoJSONTag       = "go_json_tag"       // json tag of go struct fields: original, snake or camel
+	GoReuse         = "go_reuse"          // decode go messages in place, reusing memory of fields and pooled messages
+	GoSplitFiles    = "go_split_files"    // generate one go file for each message, enum and service of schema
+	GoMotan         = "go_motan"          // generate adapters of go services over the client and provider of motan-go
+	TagPrefix       = "tag."              // prefix of field options which are struct tags, such as `tag.db = user_name`
+	Sensitive       = "sensitive"         // field option which hides the value of field in toString of java messages
 	Alias           = "alias"
 	// motan config
 	WithMotanConfig       = "with_motan_config"
@@ -66,9 +79,54 @@ type CodeTemplate interface {
 	Name() string
 }
 
+//Option is an option accepted by a CodeTemplate
+type Option struct {
+	Name        string // name in the namespace of the template, such as `package` of `java.package`
+	Key         string // key in options of schemas and messages, such as `java_package`
+	Description string
+}
+
+//OptionTemplate is a CodeTemplate which declares the options it accepts.
+//only the declared options are passed to it, so options of other templates will not leak into it.
+type OptionTemplate interface {
+	CodeTemplate
+	Options() []*Option
+}
+
+//CacheKeyTemplate is a CodeTemplate whose generated code depends on more than schemas and options,
+//such as template files, plugin executables or the go module of output directory.
+//CacheKey returns a digest of them, the cached code of the template is regenerated when it changes.
+type CacheKeyTemplate interface {
+	CodeTemplate
+	CacheKey(context *Context) string
+}
+
+//Output is the destination of generated files, such as a directory, memory or an archive.
+//Write is called sequentially, and Close is called once after all files are written.
+type Output interface {
+	Write(file *GeneratedFile) error
+	Close() error
+}
+
+//DirectoryOutput is an Output which writes the files of each template into a directory,
+//templates can use the directory to find the environment of generated code, such as go.mod.
+type DirectoryOutput interface {
+	Output
+	Dir(template string) string
+}
+
+//GeneratedFile is a file generated by a CodeTemplate
+type GeneratedFile struct {
+	Template string // name of the template which generates this file
+	Schema   string // name of the schema which this file is generated from
+	Name     string // file path relative to the template directory
+	Content  []byte
+}
+
 //Schema describe a breeze message.
 type Schema struct {
 	Name        string // file name
+	Hash        string // content hash of schema file
 	Package     string // file package
 	OrgPackage  string // schema name package.
 	Options     map[string]string
@@ -90,9 +148,10 @@ type Message struct {
 
 //Field is a breeze message field.
 type Field struct {
-	Index int
-	Name  string
-	Type  *Type
+	Index   int
+	Name    string
+	Type    *Type
+	Options map[string]string // options after field index, such as `string name = 1 (tag.db = user_name);`
 }
 
 //Type : message field type
@@ -130,15 +189,94 @@ type Config struct {
 	Options map[string]string
 }
 
-//Context : generate context
+//Context : generate context. Schemas and Messages should be accessed by methods of Context when used concurrently.
 type Context struct {
-	WritePath string
-	Parser    Parser
-	RPCType   string
-	Templates []CodeTemplate
-	Schemas   map[string]*Schema
-	Messages  map[string]*Message
-	Options   map[string]string
+	WritePath       string
+	Parser          Parser
+	RPCType         string
+	Templates       []CodeTemplate
+	Schemas         map[string]*Schema
+	Messages        map[string]*Message
+	Options         map[string]string
+	TemplateOptions map[string]map[string]string // options of each template, resolved from namespaced options such as `java.package`
+	Concurrency     int                          // max goroutines for parsing and generating
+	UniformPackage  string                       // if not empty, used as the package of all schemas, and package of message types will be removed
+	Output          Output                       // where generated files are written to
+	lock            sync.RWMutex
+	valueLock       sync.Mutex
+	values          map[interface{}]*contextValue // values derived from schemas, cleared when a schema is added
+}
+
+//AddSchema : add schema and its messages into context. it is safe for concurrent use
+func (c *Context) AddSchema(schema *Schema) {
+	c.lock.Lock()
+	if c.Schemas == nil {
+		c.Schemas = make(map[string]*Schema)
+	}
+	if c.Messages == nil {
+		c.Messages = make(map[string]*Message)
+	}
+	c.Schemas[schema.Name] = schema
+	for key, value := range schema.Messages {
+		c.Messages[schema.Package+"."+key] = value
+	}
+	c.lock.Unlock()
+	c.valueLock.Lock()
+	c.values = nil
+	c.valueLock.Unlock()
+}
+
+//Value : get the value of key derived from schemas, such as import aliases of all schemas.
+//it is created by create at most once until a schema is added, and create may get values of other keys. it is safe for concurrent use
+func (c *Context) Value(key interface{}, create func() interface{}) interface{} {
+	c.valueLock.Lock()
+	value, ok := c.values[key]
+	if !ok {
+		value = &contextValue{}
+		if c.values == nil {
+			c.values = make(map[interface{}]*contextValue)
+		}
+		c.values[key] = value
+	}
+	c.valueLock.Unlock()
+	value.once.Do(func() { value.value = create() })
+	return value.value
+}
+
+// contextValue is a value of context created once
+type contextValue struct {
+	once  sync.Once
+	value interface{}
+}
+
+//GetSchema : get schema by schema name. it is safe for concurrent use
+func (c *Context) GetSchema(name string) *Schema {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.Schemas[name]
+}
+
+//GetMessage : get message by full name(with package). it is safe for concurrent use
+func (c *Context) GetMessage(name string) *Message {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.Messages[name]
+}
+
+//SortedSchemas : get all schemas sorted by schema name. it is safe for concurrent use
+func (c *Context) SortedSchemas() []*Schema {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	names := make([]string, 0, len(c.Schemas))
+	for name := range c.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	schemas := make([]*Schema, 0, len(names))
+	for _, name := range names {
+		schemas = append(schemas, c.Schemas[name])
+	}
+	return schemas
 }
 
 // MotanConfig contains motan rpc configs of server end and client end.
@@ -0,0 +1,41 @@
+package core_test
+
+import (
+	"sync"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+func TestContextValue(t *testing.T) {
+	assert := assert2.New(t)
+	context := &core.Context{}
+	created := 0
+	create := func() interface{} {
+		created++
+		return len(context.SortedSchemas())
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(0, context.Value("schemas", create))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(1, created)
+
+	// values are created again after a schema is added
+	context.AddSchema(&core.Schema{Name: "a.breeze", Package: "a"})
+	assert.Equal(1, context.Value("schemas", create))
+	assert.Equal(1, context.Value("schemas", create))
+	assert.Equal(2, created)
+
+	// values may be created from other values
+	assert.Equal(2, context.Value("nested", func() interface{} {
+		return context.Value("schemas", create).(int) + 1
+	}))
+	assert.Equal(2, created)
+}
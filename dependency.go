@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+// dependencies returns the names of schemas which each schema depends on through referenced message types.
+// the referenced message types which can not be found in context are also returned, so they can be part of cache keys.
+func dependencies(context *core.Context) (deps map[string][]string, missing map[string][]string) {
+	owners := make(map[string]string) // full message name -> schema name
+	schemas := context.SortedSchemas()
+	for _, schema := range schemas {
+		for name := range schema.Messages {
+			owners[schema.Package+"."+name] = schema.Name
+		}
+	}
+	deps = make(map[string][]string, len(schemas))
+	missing = make(map[string][]string)
+	for _, schema := range schemas {
+		depSet := make(map[string]bool)
+		missingSet := make(map[string]bool)
+		for _, name := range referencedTypes(schema) {
+			if owner, ok := owners[name]; ok {
+				if owner != schema.Name {
+					depSet[owner] = true
+				}
+			} else {
+				missingSet[name] = true
+			}
+		}
+		deps[schema.Name] = sortedKeys(depSet)
+		if len(missingSet) > 0 {
+			missing[schema.Name] = sortedKeys(missingSet)
+		}
+	}
+	return deps, missing
+}
+
+// dependencyClosure returns the names of schemas which each schema depends on directly or indirectly,
+// and the missing types referenced by the schema or any of them. generated code can embed resolved types
+// of indirect dependencies, such as descriptors, so the whole closure is part of cache keys.
+func dependencyClosure(deps map[string][]string, missing map[string][]string) (closure map[string][]string, closureMissing map[string][]string) {
+	closure = make(map[string][]string, len(deps))
+	closureMissing = make(map[string][]string)
+	for name := range deps {
+		visited := map[string]bool{name: true}
+		queue := []string{name}
+		missingSet := make(map[string]bool)
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, m := range missing[current] {
+				missingSet[m] = true
+			}
+			for _, dep := range deps[current] {
+				if !visited[dep] {
+					visited[dep] = true
+					queue = append(queue, dep)
+				}
+			}
+		}
+		delete(visited, name)
+		closure[name] = sortedKeys(visited)
+		if len(missingSet) > 0 {
+			closureMissing[name] = sortedKeys(missingSet)
+		}
+	}
+	return closure, closureMissing
+}
+
+// referencedTypes returns full names of all message types referenced by messages and services of schema.
+func referencedTypes(schema *core.Schema) []string {
+	names := make([]string, 0, 16)
+	for _, message := range schema.Messages {
+		for _, field := range message.Fields {
+			names = appendTypeNames(names, field.Type, schema)
+		}
+	}
+	for _, service := range schema.Services {
+		for _, method := range service.Methods {
+			if method.Return != nil {
+				names = appendTypeNames(names, method.Return, schema)
+			}
+			for _, param := range method.Params {
+				names = appendTypeNames(names, param.Type, schema)
+			}
+		}
+	}
+	return names
+}
+
+func appendTypeNames(names []string, tp *core.Type, schema *core.Schema) []string {
+	switch tp.Number {
+	case core.Map:
+		names = appendTypeNames(names, tp.KeyType, schema)
+		names = appendTypeNames(names, tp.ValueType, schema)
+	case core.Array:
+		names = appendTypeNames(names, tp.ValueType, schema)
+	case core.Msg:
+		name := tp.Name
+		if !strings.Contains(name, ".") {
+			name = schema.Package + "." + name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,34 @@
+package generator
+
+import (
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func TestCacheDependencyClosure(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{
+		"a.breeze": "package test.a;\nmessage A {\n    test.b.B b = 1;\n}\n",
+		"b.breeze": "package test.b;\nmessage B {\n    test.c.C c = 1;\n}\n",
+		"c.breeze": "package test.c;\nmessage C {\n    int32 id = 1;\n}\n",
+		"d.breeze": "package test.d;\nmessage D {\n    int32 id = 1;\n}\n",
+	}
+	cache, _ := NewCache("")
+	_, _, err := GeneratByFileContent(files, &Config{CodeTemplates: "java", Cache: cache})
+	assert.Nil(err)
+
+	// a depends on c through b, so it is regenerated too
+	files["c.breeze"] = "package test.c;\nenum C {\n    X = 1;\n}\n"
+	_, _, err = GeneratByFileContent(files, &Config{CodeTemplates: "java", Cache: cache})
+	assert.Nil(err)
+	hits, misses := cache.Stats()
+	assert.Equal(1, hits)
+	assert.Equal(7, misses)
+
+	deps, missing := dependencyClosure(map[string][]string{"a": {"b"}, "b": {"c", "a"}, "c": nil}, map[string][]string{"c": {"test.x.X"}})
+	assert.Equal([]string{"b", "c"}, deps["a"])
+	assert.Equal([]string{"a", "c"}, deps["b"])
+	assert.Empty(deps["c"])
+	assert.Equal([]string{"test.x.X"}, missing["a"])
+}
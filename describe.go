@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"errors"
+
+	"github.com/weibreeze/breeze-generator/core"
+	"github.com/weibreeze/breeze-generator/descriptor"
+)
+
+//Describe parses all schema files in paths and describes them with the options of config, no code is generated.
+//a path can be a directory or a schema file. the result can be encoded as stable json by descriptor.Marshal.
+func Describe(paths []string, config *Config) (*descriptor.SchemaSet, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no schema path")
+	}
+	if config == nil {
+		config = &Config{}
+	}
+	context, err := initContext(config)
+	if err != nil {
+		return nil, err
+	}
+	if err = parsePaths(paths, config, context); err != nil {
+		return nil, err
+	}
+	return DescribeContext(context), nil
+}
+
+//DescribeContext describes all schemas in a parsed context. options of schemas and messages are the effective options,
+//which are merged with the options for all templates. namespaced options of templates are described separately.
+func DescribeContext(context *core.Context) *descriptor.SchemaSet {
+	set := descriptor.FromContext(optionView(context, context.Options))
+	if len(context.Options) > 0 {
+		set.Options = context.Options
+	}
+	for name, options := range context.TemplateOptions {
+		if len(options) == 0 {
+			continue
+		}
+		if set.TemplateOptions == nil {
+			set.TemplateOptions = make(map[string]map[string]string)
+		}
+		set.TemplateOptions[name] = options
+	}
+	return set
+}
@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	"github.com/weibreeze/breeze-generator/core"
+	"github.com/weibreeze/breeze-generator/descriptor"
+)
+
+func TestDescribe(t *testing.T) {
+	assert := assert2.New(t)
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.breeze"), []byte("package test.a;\nmessage A {\n    test.b.E e = 2;\n    map<string, test.b.B> m = 1;\n}\nservice S {\n    get(int32 id)A;\n}\nconfig SMotanConfig {\n    registry = vintage;\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.breeze"), []byte("package test.b;\nmessage B {\n    int32 id = 1;\n}\nenum E {\n    X = 2;\n    Y = 1;\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := map[string]string{core.WithPackageDir: "true", core.WithMotanConfig: "true", "java.package": "com.weibo.test"}
+	set, err := Describe([]string{dir}, &Config{Options: options})
+	assert.Nil(err)
+	assert.Equal(descriptor.Version, set.Version)
+	assert.Equal(map[string]string{core.WithPackageDir: "true", core.WithMotanConfig: "true"}, set.Options)
+	assert.Equal("com.weibo.test", set.TemplateOptions["java"][core.JavaPackage])
+	assert.Equal(2, len(set.Schemas))
+	a := set.Schemas[0]
+	assert.Equal("a.breeze", a.Name)
+	assert.Equal("true", a.Options[core.WithPackageDir])
+	assert.Equal("", a.Options[core.JavaPackage])
+	assert.Equal(1, a.Messages[0].Fields[0].Index)
+	assert.Equal(&descriptor.Type{Kind: descriptor.KindMessage, Name: "test.b.B"}, a.Messages[0].Fields[0].Type.Value)
+	assert.Equal(&descriptor.Type{Kind: descriptor.KindEnum, Name: "test.b.E"}, a.Messages[0].Fields[1].Type)
+	assert.Equal("test.a.A", a.Services[0].Methods[0].Return.Name)
+	assert.Equal("vintage", a.Configs[0].Options["registry"])
+	assert.Equal("com.weibo.test.S", a.Motan.Services["S"]["interface"]) // java package is used by motan config
+	assert.Equal("Y", set.Schemas[1].Messages[1].EnumValues[0].Name)
+
+	// the json is stable
+	content, err := descriptor.Marshal(set)
+	assert.Nil(err)
+	for i := 0; i < 5; i++ {
+		set, err = Describe([]string{dir}, &Config{Options: options})
+		assert.Nil(err)
+		content2, err := descriptor.Marshal(set)
+		assert.Nil(err)
+		assert.Equal(string(content), string(content2))
+	}
+}
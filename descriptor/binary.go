@@ -0,0 +1,627 @@
+package descriptor
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/weibreeze/breeze-go"
+)
+
+//binary descriptor sets are SchemaSet messages encoded by breeze, the messages are declared in descriptor.breeze.
+//template options and motan config are not included, they are only described in json.
+
+//BinaryPackage is the breeze package of descriptor messages
+const BinaryPackage = "breeze.descriptor"
+
+var (
+	schemaSetBreezeSchema = &breeze.Schema{Name: BinaryPackage + ".SchemaSet"}
+	schemaBreezeSchema    = &breeze.Schema{Name: BinaryPackage + ".Schema"}
+	messageBreezeSchema   = &breeze.Schema{Name: BinaryPackage + ".Message"}
+	fieldBreezeSchema     = &breeze.Schema{Name: BinaryPackage + ".Field"}
+	enumValueBreezeSchema = &breeze.Schema{Name: BinaryPackage + ".EnumValue"}
+	typeBreezeSchema      = &breeze.Schema{Name: BinaryPackage + ".Type"}
+	serviceBreezeSchema   = &breeze.Schema{Name: BinaryPackage + ".Service"}
+	methodBreezeSchema    = &breeze.Schema{Name: BinaryPackage + ".Method"}
+	paramBreezeSchema     = &breeze.Schema{Name: BinaryPackage + ".Param"}
+	configBreezeSchema    = &breeze.Schema{Name: BinaryPackage + ".Config"}
+)
+
+func init() {
+	schemaSetBreezeSchema.PutFields(&breeze.Field{Index: 1, Name: "version", Type: "int32"},
+		&breeze.Field{Index: 2, Name: "options", Type: "map<string, string>"},
+		&breeze.Field{Index: 3, Name: "schemas", Type: "array<Schema>"})
+	schemaBreezeSchema.PutFields(&breeze.Field{Index: 1, Name: "name", Type: "string"},
+		&breeze.Field{Index: 2, Name: "packageName", Type: "string"},
+		&breeze.Field{Index: 3, Name: "hash", Type: "string"},
+		&breeze.Field{Index: 4, Name: "options", Type: "map<string, string>"},
+		&breeze.Field{Index: 5, Name: "messages", Type: "array<Message>"},
+		&breeze.Field{Index: 6, Name: "services", Type: "array<Service>"},
+		&breeze.Field{Index: 7, Name: "configs", Type: "array<Config>"})
+	messageBreezeSchema.PutFields(&breeze.Field{Index: 1, Name: "name", Type: "string"},
+		&breeze.Field{Index: 2, Name: "fullName", Type: "string"},
+		&breeze.Field{Index: 3, Name: "alias", Type: "string"},
+		&breeze.Field{Index: 4, Name: "isEnum", Type: "bool"},
+		&breeze.Field{Index: 5, Name: "options", Type: "map<string, string>"},
+		&breeze.Field{Index: 6, Name: "fields", Type: "array<Field>"},
+		&breeze.Field{Index: 7, Name: "enumValues", Type: "array<EnumValue>"})
+	fieldBreezeSchema.PutFields(&breeze.Field{Index: 1, Name: "index", Type: "int32"},
+		&breeze.Field{Index: 2, Name: "name", Type: "string"},
+		&breeze.Field{Index: 3, Name: "type", Type: "Type"},
+		&breeze.Field{Index: 4, Name: "options", Type: "map<string, string>"})
+	enumValueBreezeSchema.PutFields(&breeze.Field{Index: 1, Name: "number", Type: "int32"},
+		&breeze.Field{Index: 2, Name: "name", Type: "string"})
+	typeBreezeSchema.PutFields(&breeze.Field{Index: 1, Name: "kind", Type: "string"},
+		&breeze.Field{Index: 2, Name: "name", Type: "string"},
+		&breeze.Field{Index: 3, Name: "keyType", Type: "Type"},
+		&breeze.Field{Index: 4, Name: "valueType", Type: "Type"})
+	serviceBreezeSchema.PutFields(&breeze.Field{Index: 1, Name: "name", Type: "string"},
+		&breeze.Field{Index: 2, Name: "fullName", Type: "string"},
+		&breeze.Field{Index: 3, Name: "options", Type: "map<string, string>"},
+		&breeze.Field{Index: 4, Name: "methods", Type: "array<Method>"})
+	methodBreezeSchema.PutFields(&breeze.Field{Index: 1, Name: "name", Type: "string"},
+		&breeze.Field{Index: 2, Name: "params", Type: "array<Param>"},
+		&breeze.Field{Index: 3, Name: "returnType", Type: "Type"})
+	paramBreezeSchema.PutFields(&breeze.Field{Index: 1, Name: "index", Type: "int32"},
+		&breeze.Field{Index: 2, Name: "name", Type: "string"},
+		&breeze.Field{Index: 3, Name: "type", Type: "Type"})
+	configBreezeSchema.PutFields(&breeze.Field{Index: 1, Name: "name", Type: "string"},
+		&breeze.Field{Index: 2, Name: "options", Type: "map<string, string>"})
+}
+
+//MarshalBinary encodes a schema set by breeze. the result is stable for the same schema set
+func MarshalBinary(set *SchemaSet) ([]byte, error) {
+	buf := breeze.NewBuffer(1024)
+	if err := breeze.WriteValue(buf, set); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+//UnmarshalBinary decodes a schema set encoded by MarshalBinary
+func UnmarshalBinary(data []byte) (*SchemaSet, error) {
+	set := &SchemaSet{}
+	if err := breeze.ReadByMessage(breeze.CreateBuffer(data), set); err != nil {
+		return nil, err
+	}
+	if set.Version != Version {
+		return nil, errors.New("unsupported descriptor version: " + strconv.Itoa(set.Version))
+	}
+	return set, nil
+}
+
+//WriteTo : write schema set by breeze
+func (s *SchemaSet) WriteTo(buf *breeze.Buffer) error {
+	return breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		breeze.WriteInt32Field(buf, 1, int32(s.Version))
+		writeOptionsField(buf, 2, s.Options)
+		writeMessagesField(buf, 3, len(s.Schemas), func(i int) breeze.Message { return s.Schemas[i] })
+	})
+}
+
+//ReadFrom : read schema set by breeze
+func (s *SchemaSet) ReadFrom(buf *breeze.Buffer) error {
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+		switch index {
+		case 1:
+			s.Version, err = readInt(buf)
+		case 2:
+			s.Options, err = breeze.ReadStringStringMap(buf, true)
+		case 3:
+			err = readMessagesField(buf, func() breeze.Message {
+				schema := &Schema{}
+				s.Schemas = append(s.Schemas, schema)
+				return schema
+			})
+		default: //skip unknown field
+			_, err = breeze.ReadValue(buf, nil)
+		}
+		return err
+	})
+}
+
+//GetName : breeze message name
+func (s *SchemaSet) GetName() string {
+	return schemaSetBreezeSchema.Name
+}
+
+//GetAlias : breeze message alias
+func (s *SchemaSet) GetAlias() string {
+	return schemaSetBreezeSchema.Alias
+}
+
+//GetSchema : breeze schema of message
+func (s *SchemaSet) GetSchema() *breeze.Schema {
+	return schemaSetBreezeSchema
+}
+
+//WriteTo : write schema by breeze
+func (s *Schema) WriteTo(buf *breeze.Buffer) error {
+	return breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		breeze.WriteStringField(buf, 1, s.Name)
+		breeze.WriteStringField(buf, 2, s.Package)
+		breeze.WriteStringField(buf, 3, s.Hash)
+		writeOptionsField(buf, 4, s.Options)
+		writeMessagesField(buf, 5, len(s.Messages), func(i int) breeze.Message { return s.Messages[i] })
+		writeMessagesField(buf, 6, len(s.Services), func(i int) breeze.Message { return s.Services[i] })
+		writeMessagesField(buf, 7, len(s.Configs), func(i int) breeze.Message { return s.Configs[i] })
+	})
+}
+
+//ReadFrom : read schema by breeze
+func (s *Schema) ReadFrom(buf *breeze.Buffer) error {
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+		switch index {
+		case 1:
+			err = breeze.ReadString(buf, &s.Name)
+		case 2:
+			err = breeze.ReadString(buf, &s.Package)
+		case 3:
+			err = breeze.ReadString(buf, &s.Hash)
+		case 4:
+			s.Options, err = breeze.ReadStringStringMap(buf, true)
+		case 5:
+			err = readMessagesField(buf, func() breeze.Message {
+				message := &Message{}
+				s.Messages = append(s.Messages, message)
+				return message
+			})
+		case 6:
+			err = readMessagesField(buf, func() breeze.Message {
+				service := &Service{}
+				s.Services = append(s.Services, service)
+				return service
+			})
+		case 7:
+			err = readMessagesField(buf, func() breeze.Message {
+				config := &Config{}
+				s.Configs = append(s.Configs, config)
+				return config
+			})
+		default: //skip unknown field
+			_, err = breeze.ReadValue(buf, nil)
+		}
+		return err
+	})
+}
+
+//GetName : breeze message name
+func (s *Schema) GetName() string {
+	return schemaBreezeSchema.Name
+}
+
+//GetAlias : breeze message alias
+func (s *Schema) GetAlias() string {
+	return schemaBreezeSchema.Alias
+}
+
+//GetSchema : breeze schema of message
+func (s *Schema) GetSchema() *breeze.Schema {
+	return schemaBreezeSchema
+}
+
+//WriteTo : write message descriptor by breeze
+func (m *Message) WriteTo(buf *breeze.Buffer) error {
+	return breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		breeze.WriteStringField(buf, 1, m.Name)
+		breeze.WriteStringField(buf, 2, m.FullName)
+		breeze.WriteStringField(buf, 3, m.Alias)
+		breeze.WriteBoolField(buf, 4, m.Enum)
+		writeOptionsField(buf, 5, m.Options)
+		writeMessagesField(buf, 6, len(m.Fields), func(i int) breeze.Message { return m.Fields[i] })
+		writeMessagesField(buf, 7, len(m.EnumValues), func(i int) breeze.Message { return m.EnumValues[i] })
+	})
+}
+
+//ReadFrom : read message descriptor by breeze
+func (m *Message) ReadFrom(buf *breeze.Buffer) error {
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+		switch index {
+		case 1:
+			err = breeze.ReadString(buf, &m.Name)
+		case 2:
+			err = breeze.ReadString(buf, &m.FullName)
+		case 3:
+			err = breeze.ReadString(buf, &m.Alias)
+		case 4:
+			err = breeze.ReadBool(buf, &m.Enum)
+		case 5:
+			m.Options, err = breeze.ReadStringStringMap(buf, true)
+		case 6:
+			err = readMessagesField(buf, func() breeze.Message {
+				field := &Field{}
+				m.Fields = append(m.Fields, field)
+				return field
+			})
+		case 7:
+			err = readMessagesField(buf, func() breeze.Message {
+				value := &EnumValue{}
+				m.EnumValues = append(m.EnumValues, value)
+				return value
+			})
+		default: //skip unknown field
+			_, err = breeze.ReadValue(buf, nil)
+		}
+		return err
+	})
+}
+
+//GetName : breeze message name
+func (m *Message) GetName() string {
+	return messageBreezeSchema.Name
+}
+
+//GetAlias : breeze message alias
+func (m *Message) GetAlias() string {
+	return messageBreezeSchema.Alias
+}
+
+//GetSchema : breeze schema of message
+func (m *Message) GetSchema() *breeze.Schema {
+	return messageBreezeSchema
+}
+
+//WriteTo : write field descriptor by breeze
+func (f *Field) WriteTo(buf *breeze.Buffer) error {
+	return breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		breeze.WriteInt32Field(buf, 1, int32(f.Index))
+		breeze.WriteStringField(buf, 2, f.Name)
+		if f.Type != nil {
+			breeze.WriteMessageField(buf, 3, f.Type)
+		}
+		writeOptionsField(buf, 4, f.Options)
+	})
+}
+
+//ReadFrom : read field descriptor by breeze
+func (f *Field) ReadFrom(buf *breeze.Buffer) error {
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+		switch index {
+		case 1:
+			f.Index, err = readInt(buf)
+		case 2:
+			err = breeze.ReadString(buf, &f.Name)
+		case 3:
+			f.Type = &Type{}
+			err = breeze.ReadByMessage(buf, f.Type)
+		case 4:
+			f.Options, err = breeze.ReadStringStringMap(buf, true)
+		default: //skip unknown field
+			_, err = breeze.ReadValue(buf, nil)
+		}
+		return err
+	})
+}
+
+//GetName : breeze message name
+func (f *Field) GetName() string {
+	return fieldBreezeSchema.Name
+}
+
+//GetAlias : breeze message alias
+func (f *Field) GetAlias() string {
+	return fieldBreezeSchema.Alias
+}
+
+//GetSchema : breeze schema of message
+func (f *Field) GetSchema() *breeze.Schema {
+	return fieldBreezeSchema
+}
+
+//WriteTo : write enum value descriptor by breeze
+func (e *EnumValue) WriteTo(buf *breeze.Buffer) error {
+	return breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		breeze.WriteInt32Field(buf, 1, int32(e.Number))
+		breeze.WriteStringField(buf, 2, e.Name)
+	})
+}
+
+//ReadFrom : read enum value descriptor by breeze
+func (e *EnumValue) ReadFrom(buf *breeze.Buffer) error {
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+		switch index {
+		case 1:
+			e.Number, err = readInt(buf)
+		case 2:
+			err = breeze.ReadString(buf, &e.Name)
+		default: //skip unknown field
+			_, err = breeze.ReadValue(buf, nil)
+		}
+		return err
+	})
+}
+
+//GetName : breeze message name
+func (e *EnumValue) GetName() string {
+	return enumValueBreezeSchema.Name
+}
+
+//GetAlias : breeze message alias
+func (e *EnumValue) GetAlias() string {
+	return enumValueBreezeSchema.Alias
+}
+
+//GetSchema : breeze schema of message
+func (e *EnumValue) GetSchema() *breeze.Schema {
+	return enumValueBreezeSchema
+}
+
+//WriteTo : write type descriptor by breeze
+func (t *Type) WriteTo(buf *breeze.Buffer) error {
+	return breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		breeze.WriteStringField(buf, 1, t.Kind)
+		breeze.WriteStringField(buf, 2, t.Name)
+		if t.Key != nil {
+			breeze.WriteMessageField(buf, 3, t.Key)
+		}
+		if t.Value != nil {
+			breeze.WriteMessageField(buf, 4, t.Value)
+		}
+	})
+}
+
+//ReadFrom : read type descriptor by breeze
+func (t *Type) ReadFrom(buf *breeze.Buffer) error {
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+		switch index {
+		case 1:
+			err = breeze.ReadString(buf, &t.Kind)
+		case 2:
+			err = breeze.ReadString(buf, &t.Name)
+		case 3:
+			t.Key = &Type{}
+			err = breeze.ReadByMessage(buf, t.Key)
+		case 4:
+			t.Value = &Type{}
+			err = breeze.ReadByMessage(buf, t.Value)
+		default: //skip unknown field
+			_, err = breeze.ReadValue(buf, nil)
+		}
+		return err
+	})
+}
+
+//GetName : breeze message name
+func (t *Type) GetName() string {
+	return typeBreezeSchema.Name
+}
+
+//GetAlias : breeze message alias
+func (t *Type) GetAlias() string {
+	return typeBreezeSchema.Alias
+}
+
+//GetSchema : breeze schema of message
+func (t *Type) GetSchema() *breeze.Schema {
+	return typeBreezeSchema
+}
+
+//WriteTo : write service descriptor by breeze
+func (s *Service) WriteTo(buf *breeze.Buffer) error {
+	return breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		breeze.WriteStringField(buf, 1, s.Name)
+		breeze.WriteStringField(buf, 2, s.FullName)
+		writeOptionsField(buf, 3, s.Options)
+		writeMessagesField(buf, 4, len(s.Methods), func(i int) breeze.Message { return s.Methods[i] })
+	})
+}
+
+//ReadFrom : read service descriptor by breeze
+func (s *Service) ReadFrom(buf *breeze.Buffer) error {
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+		switch index {
+		case 1:
+			err = breeze.ReadString(buf, &s.Name)
+		case 2:
+			err = breeze.ReadString(buf, &s.FullName)
+		case 3:
+			s.Options, err = breeze.ReadStringStringMap(buf, true)
+		case 4:
+			err = readMessagesField(buf, func() breeze.Message {
+				method := &Method{}
+				s.Methods = append(s.Methods, method)
+				return method
+			})
+		default: //skip unknown field
+			_, err = breeze.ReadValue(buf, nil)
+		}
+		return err
+	})
+}
+
+//GetName : breeze message name
+func (s *Service) GetName() string {
+	return serviceBreezeSchema.Name
+}
+
+//GetAlias : breeze message alias
+func (s *Service) GetAlias() string {
+	return serviceBreezeSchema.Alias
+}
+
+//GetSchema : breeze schema of message
+func (s *Service) GetSchema() *breeze.Schema {
+	return serviceBreezeSchema
+}
+
+//WriteTo : write method descriptor by breeze
+func (m *Method) WriteTo(buf *breeze.Buffer) error {
+	return breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		breeze.WriteStringField(buf, 1, m.Name)
+		writeMessagesField(buf, 2, len(m.Params), func(i int) breeze.Message { return m.Params[i] })
+		if m.Return != nil {
+			breeze.WriteMessageField(buf, 3, m.Return)
+		}
+	})
+}
+
+//ReadFrom : read method descriptor by breeze
+func (m *Method) ReadFrom(buf *breeze.Buffer) error {
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+		switch index {
+		case 1:
+			err = breeze.ReadString(buf, &m.Name)
+		case 2:
+			err = readMessagesField(buf, func() breeze.Message {
+				param := &Param{}
+				m.Params = append(m.Params, param)
+				return param
+			})
+		case 3:
+			m.Return = &Type{}
+			err = breeze.ReadByMessage(buf, m.Return)
+		default: //skip unknown field
+			_, err = breeze.ReadValue(buf, nil)
+		}
+		return err
+	})
+}
+
+//GetName : breeze message name
+func (m *Method) GetName() string {
+	return methodBreezeSchema.Name
+}
+
+//GetAlias : breeze message alias
+func (m *Method) GetAlias() string {
+	return methodBreezeSchema.Alias
+}
+
+//GetSchema : breeze schema of message
+func (m *Method) GetSchema() *breeze.Schema {
+	return methodBreezeSchema
+}
+
+//WriteTo : write param descriptor by breeze
+func (p *Param) WriteTo(buf *breeze.Buffer) error {
+	return breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		breeze.WriteInt32Field(buf, 1, int32(p.Index))
+		breeze.WriteStringField(buf, 2, p.Name)
+		if p.Type != nil {
+			breeze.WriteMessageField(buf, 3, p.Type)
+		}
+	})
+}
+
+//ReadFrom : read param descriptor by breeze
+func (p *Param) ReadFrom(buf *breeze.Buffer) error {
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+		switch index {
+		case 1:
+			p.Index, err = readInt(buf)
+		case 2:
+			err = breeze.ReadString(buf, &p.Name)
+		case 3:
+			p.Type = &Type{}
+			err = breeze.ReadByMessage(buf, p.Type)
+		default: //skip unknown field
+			_, err = breeze.ReadValue(buf, nil)
+		}
+		return err
+	})
+}
+
+//GetName : breeze message name
+func (p *Param) GetName() string {
+	return paramBreezeSchema.Name
+}
+
+//GetAlias : breeze message alias
+func (p *Param) GetAlias() string {
+	return paramBreezeSchema.Alias
+}
+
+//GetSchema : breeze schema of message
+func (p *Param) GetSchema() *breeze.Schema {
+	return paramBreezeSchema
+}
+
+//WriteTo : write config descriptor by breeze
+func (c *Config) WriteTo(buf *breeze.Buffer) error {
+	return breeze.WriteMessageWithoutType(buf, func(buf *breeze.Buffer) {
+		breeze.WriteStringField(buf, 1, c.Name)
+		writeOptionsField(buf, 2, c.Options)
+	})
+}
+
+//ReadFrom : read config descriptor by breeze
+func (c *Config) ReadFrom(buf *breeze.Buffer) error {
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {
+		switch index {
+		case 1:
+			err = breeze.ReadString(buf, &c.Name)
+		case 2:
+			c.Options, err = breeze.ReadStringStringMap(buf, true)
+		default: //skip unknown field
+			_, err = breeze.ReadValue(buf, nil)
+		}
+		return err
+	})
+}
+
+//GetName : breeze message name
+func (c *Config) GetName() string {
+	return configBreezeSchema.Name
+}
+
+//GetAlias : breeze message alias
+func (c *Config) GetAlias() string {
+	return configBreezeSchema.Alias
+}
+
+//GetSchema : breeze schema of message
+func (c *Config) GetSchema() *breeze.Schema {
+	return configBreezeSchema
+}
+
+// writeOptionsField writes options sorted by key, so the encoded descriptor is stable
+func writeOptionsField(buf *breeze.Buffer, index int, options map[string]string) {
+	if len(options) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	breeze.WriteMapField(buf, index, len(keys), func(buf *breeze.Buffer) {
+		breeze.WriteStringType(buf)
+		breeze.WriteStringType(buf)
+		for _, k := range keys {
+			breeze.WriteString(buf, k, false)
+			breeze.WriteString(buf, options[k], false)
+		}
+	})
+}
+
+func writeMessagesField(buf *breeze.Buffer, index int, size int, elem func(i int) breeze.Message) {
+	if size == 0 {
+		return
+	}
+	breeze.WriteArrayField(buf, index, size, func(buf *breeze.Buffer) {
+		for i := 0; i < size; i++ {
+			message := elem(i)
+			if i == 0 {
+				breeze.WriteMessageType(buf, message.GetName())
+			}
+			message.WriteTo(buf)
+		}
+	})
+}
+
+// readMessagesField reads an array of messages, newElem returns a new element which is appended to the array
+func readMessagesField(buf *breeze.Buffer, newElem func() breeze.Message) error {
+	size, err := breeze.ReadPackedSize(buf, true)
+	if err != nil {
+		return err
+	}
+	return breeze.ReadPacked(buf, size, false, func(buf *breeze.Buffer) error {
+		return newElem().ReadFrom(buf)
+	})
+}
+
+func readInt(buf *breeze.Buffer) (int, error) {
+	var i int32
+	err := breeze.ReadInt32(buf, &i)
+	return int(i), err
+}
@@ -0,0 +1,191 @@
+package descriptor_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	generator "github.com/weibreeze/breeze-generator"
+	"github.com/weibreeze/breeze-generator/core"
+	"github.com/weibreeze/breeze-generator/descriptor"
+	"github.com/weibreeze/breeze-go"
+)
+
+func TestBinary(t *testing.T) {
+	assert := assert2.New(t)
+	// descriptor.breeze describes itself
+	set, err := generator.Describe([]string{"descriptor.breeze"}, nil)
+	assert.Nil(err)
+	data, err := descriptor.MarshalBinary(set)
+	assert.Nil(err)
+	decoded, err := descriptor.UnmarshalBinary(data)
+	assert.Nil(err)
+	assert.Equal(set, decoded)
+	for i := 0; i < 5; i++ {
+		data2, err := descriptor.MarshalBinary(decoded)
+		assert.Nil(err)
+		assert.Equal(data, data2)
+	}
+
+	// breeze schemas of descriptor messages are the same as descriptor.breeze
+	messages := map[string]breeze.Message{"SchemaSet": &descriptor.SchemaSet{}, "Schema": &descriptor.Schema{}, "Message": &descriptor.Message{},
+		"Field": &descriptor.Field{}, "EnumValue": &descriptor.EnumValue{}, "Type": &descriptor.Type{}, "Service": &descriptor.Service{},
+		"Method": &descriptor.Method{}, "Param": &descriptor.Param{}, "Config": &descriptor.Config{}}
+	assert.Equal(len(messages), len(set.Schemas[0].Messages))
+	for _, message := range set.Schemas[0].Messages {
+		schema := messages[message.Name].GetSchema()
+		assert.Equal(descriptor.BinaryPackage+"."+message.Name, schema.Name)
+		for _, field := range message.Fields {
+			f := schema.GetFieldByIndex(field.Index)
+			if assert.NotNil(f, message.Name+"."+field.Name) {
+				assert.Equal(field.Name, f.Name)
+			}
+		}
+	}
+
+	_, err = descriptor.UnmarshalBinary(data[:len(data)-1])
+	assert.NotNil(err)
+}
+
+// TestBinaryFields checks that the hand-written WriteTo of descriptor messages encodes
+// every field with the index and type declared in descriptor.breeze
+func TestBinaryFields(t *testing.T) {
+	assert := assert2.New(t)
+	self, err := generator.Describe([]string{"descriptor.breeze"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	declared := make(map[string]*descriptor.Message)
+	for _, message := range self.Schemas[0].Messages {
+		declared[message.FullName] = message
+	}
+
+	// all fields of all messages are set
+	tp := &descriptor.Type{Kind: descriptor.KindMap, Name: "n", Key: &descriptor.Type{Kind: descriptor.KindString}, Value: &descriptor.Type{Kind: descriptor.KindString}}
+	options := map[string]string{"k": "v"}
+	set := &descriptor.SchemaSet{Version: descriptor.Version, Options: options, Schemas: []*descriptor.Schema{{
+		Name: "a.breeze", Package: "a", Hash: "h", Options: options,
+		Messages: []*descriptor.Message{{Name: "A", FullName: "a.A", Alias: "x", Enum: true, Options: options,
+			Fields:     []*descriptor.Field{{Index: 1, Name: "f", Type: tp, Options: options}},
+			EnumValues: []*descriptor.EnumValue{{Number: 1, Name: "X"}}}},
+		Services: []*descriptor.Service{{Name: "S", FullName: "a.S", Options: options,
+			Methods: []*descriptor.Method{{Name: "m", Params: []*descriptor.Param{{Index: 1, Name: "p", Type: tp}}, Return: tp}}}},
+		Configs: []*descriptor.Config{{Name: "c", Options: options}},
+	}}}
+	data, err := descriptor.MarshalBinary(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := descriptor.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(set, decoded)
+
+	seen := make(map[string]map[int]bool)
+	root := &fieldChecker{message: declared[descriptor.BinaryPackage+".SchemaSet"], declared: declared, seen: seen}
+	if err = breeze.ReadByMessage(breeze.CreateBuffer(data), root); err != nil {
+		t.Fatal(err)
+	}
+	for name, message := range declared {
+		for _, field := range message.Fields {
+			assert.True(seen[name][field.Index], "field %s.%s is not encoded", name, field.Name)
+		}
+	}
+}
+
+// fieldChecker reads a descriptor message encoded by breeze, and fails if a field is not declared in descriptor.breeze
+// or its type is different from the declared type. the indexes of read fields are recorded in seen
+type fieldChecker struct {
+	message  *descriptor.Message
+	declared map[string]*descriptor.Message
+	seen     map[string]map[int]bool
+}
+
+func (c *fieldChecker) ReadFrom(buf *breeze.Buffer) error {
+	return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) error {
+		var field *descriptor.Field
+		for _, f := range c.message.Fields {
+			if f.Index == index {
+				field = f
+			}
+		}
+		if field == nil {
+			return errors.New("field " + c.message.Name + "." + strconv.Itoa(index) + " is not declared")
+		}
+		if c.seen[c.message.FullName] == nil {
+			c.seen[c.message.FullName] = make(map[int]bool)
+		}
+		c.seen[c.message.FullName][index] = true
+		checker := func(tp *descriptor.Type) *fieldChecker {
+			return &fieldChecker{message: c.declared[tp.Name], declared: c.declared, seen: c.seen}
+		}
+		switch {
+		case field.Type.Kind == descriptor.KindMessage:
+			return breeze.ReadByMessage(buf, checker(field.Type))
+		case field.Type.Kind == descriptor.KindArray && field.Type.Value.Kind == descriptor.KindMessage:
+			size, err := breeze.ReadPackedSize(buf, true)
+			if err != nil {
+				return err
+			}
+			return breeze.ReadPacked(buf, size, false, checker(field.Type.Value).ReadFrom)
+		}
+		v, err := breeze.ReadValue(buf, nil)
+		if err != nil {
+			return err
+		}
+		kinds := map[string]reflect.Kind{descriptor.KindString: reflect.String, descriptor.KindInt32: reflect.Int32,
+			descriptor.KindBool: reflect.Bool, descriptor.KindMap: reflect.Map}
+		if kind := reflect.TypeOf(v).Kind(); kind != kinds[field.Type.Kind] {
+			return errors.New("field " + c.message.Name + "." + field.Name + " is encoded as " + kind.String() + " instead of " + field.Type.Kind)
+		}
+		return nil
+	})
+}
+
+func (c *fieldChecker) WriteTo(buf *breeze.Buffer) error {
+	return errors.New("fieldChecker only reads messages")
+}
+
+func (c *fieldChecker) GetName() string {
+	return c.message.FullName
+}
+
+func (c *fieldChecker) GetAlias() string {
+	return ""
+}
+
+func (c *fieldChecker) GetSchema() *breeze.Schema {
+	return nil
+}
+
+func TestEmbedDescriptor(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{"test-a.breeze": "package test.a;\nmessage A {\n    int32 id = 1;\n}\nenum E {\n    X = 1;\n}\n"}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go,java", Options: map[string]string{core.WithDescriptor: "true"}})
+	assert.Nil(err)
+	assert.Contains(code["test-a.go"], "var TestABreezeDescriptor = []byte{")
+	java := code["TestABreezeDescriptor.java"]
+	assert.Contains(java, "public static byte[] getDescriptor()")
+
+	var encoded strings.Builder
+	for _, match := range regexp.MustCompile(`(?m)^        "([A-Za-z0-9+/=]+)",$`).FindAllStringSubmatch(java, -1) {
+		encoded.WriteString(match[1])
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded.String())
+	assert.Nil(err)
+	set, err := descriptor.UnmarshalBinary(data)
+	assert.Nil(err)
+	assert.Equal("test.a.A", set.Schemas[0].Messages[0].FullName)
+	assert.Equal("X", set.Schemas[0].Messages[1].EnumValues[0].Name)
+
+	code, _, err = generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go,java"})
+	assert.Nil(err)
+	assert.NotContains(code["test-a.go"], "BreezeDescriptor")
+	assert.Equal("", code["TestABreezeDescriptor.java"])
+}
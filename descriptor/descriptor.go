@@ -0,0 +1,307 @@
+// Package descriptor describes parsed breeze schemas in a stable form which can be encoded as json,
+// so tools in other languages can use breeze schemas without parsing them.
+//
+// the json format is versioned by Version, which changes only when the format changes incompatibly.
+// new fields may be added in the same version, so readers should ignore unknown fields.
+// fields with zero values are omitted, and the output is stable for the same input:
+// messages, services, methods and configs are sorted by name, fields and params by index, enum values by number,
+// and keys of options are sorted.
+//
+//	{
+//	  "version": 1,
+//	  "options": {"with_package_dir": "true"},                 // options for all templates
+//	  "template_options": {"java": {"java_package": "com.a"}},  // namespaced options of each template
+//	  "schemas": [{
+//	    "name": "a.breeze", "package": "test.a", "hash": "<sha256 of file>",
+//	    "options": {"with_package_dir": "true"},                // options of schema merged with options for all templates
+//	    "messages": [{
+//	      "name": "A", "full_name": "test.a.A", "alias": "a",
+//	      "fields": [{"index": 1, "name": "m", "type": {"kind": "map", "key": {"kind": "string"}, "value": {"kind": "message", "name": "test.b.B"}}}]
+//	    }, {
+//	      "name": "E", "full_name": "test.a.E", "enum": true,
+//	      "enum_values": [{"number": 1, "name": "X"}]
+//	    }],
+//	    "services": [{
+//	      "name": "S", "full_name": "test.a.S",
+//	      "methods": [{"name": "get", "params": [{"index": 0, "name": "id", "type": {"kind": "int32"}}], "return": {"kind": "enum", "name": "test.a.E"}}]
+//	    }],
+//	    "configs": [{"name": "MotanConfig", "options": {"registry": "vintage"}}],
+//	    "motan_config": {"services": {"S": {"interface": "test.a.S"}}}
+//	  }]
+//	}
+//
+// type kinds are bool, string, byte, bytes, int16, int32, int64, float32, float64, map, array, message and enum.
+// name of message and enum types is the full name with package.
+package descriptor
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+//Version is the version of descriptor format, it changes when the format changes incompatibly
+const Version = 1
+
+//type kinds
+const (
+	KindBool    = "bool"
+	KindString  = "string"
+	KindByte    = "byte"
+	KindBytes   = "bytes"
+	KindInt16   = "int16"
+	KindInt32   = "int32"
+	KindInt64   = "int64"
+	KindFloat32 = "float32"
+	KindFloat64 = "float64"
+	KindMap     = "map"
+	KindArray   = "array"
+	KindMessage = "message"
+	KindEnum    = "enum"
+)
+
+var kinds = map[int]string{
+	core.Bool:    KindBool,
+	core.String:  KindString,
+	core.Byte:    KindByte,
+	core.Bytes:   KindBytes,
+	core.Int16:   KindInt16,
+	core.Int32:   KindInt32,
+	core.Int64:   KindInt64,
+	core.Float32: KindFloat32,
+	core.Float64: KindFloat64,
+	core.Map:     KindMap,
+	core.Array:   KindArray,
+	core.Msg:     KindMessage,
+}
+
+//SchemaSet is a set of schemas
+type SchemaSet struct {
+	Version         int                          `json:"version"`
+	Options         map[string]string            `json:"options,omitempty"`          // options for all templates
+	TemplateOptions map[string]map[string]string `json:"template_options,omitempty"` // namespaced options of each template
+	Schemas         []*Schema                    `json:"schemas"`
+}
+
+//Schema describes a schema file
+type Schema struct {
+	Name     string            `json:"name"`    // file name
+	Package  string            `json:"package"` // package of messages and services
+	Hash     string            `json:"hash,omitempty"`
+	Options  map[string]string `json:"options,omitempty"`
+	Messages []*Message        `json:"messages,omitempty"`
+	Services []*Service        `json:"services,omitempty"`
+	Configs  []*Config         `json:"configs,omitempty"`
+	Motan    *MotanConfig      `json:"motan_config,omitempty"` // motan rpc config built from configs and options
+}
+
+//Message describes a message or an enum
+type Message struct {
+	Name       string            `json:"name"`
+	FullName   string            `json:"full_name"` // name with package
+	Alias      string            `json:"alias,omitempty"`
+	Enum       bool              `json:"enum,omitempty"`
+	Options    map[string]string `json:"options,omitempty"`
+	Fields     []*Field          `json:"fields,omitempty"`
+	EnumValues []*EnumValue      `json:"enum_values,omitempty"`
+}
+
+//Field describes a field of message
+type Field struct {
+	Index   int               `json:"index"`
+	Name    string            `json:"name"`
+	Type    *Type             `json:"type"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+//EnumValue describes a value of enum
+type EnumValue struct {
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+}
+
+//Type describes the type of a field, a param or a return value
+type Type struct {
+	Kind  string `json:"kind"`           // one of the type kinds, such as int32, map, message or enum
+	Name  string `json:"name,omitempty"` // full name of message or enum
+	Key   *Type  `json:"key,omitempty"`  // key type of map
+	Value *Type  `json:"value,omitempty"`
+}
+
+//Service describes a rpc service
+type Service struct {
+	Name     string            `json:"name"`
+	FullName string            `json:"full_name"`
+	Options  map[string]string `json:"options,omitempty"`
+	Methods  []*Method         `json:"methods,omitempty"`
+}
+
+//Method describes a method of service
+type Method struct {
+	Name   string   `json:"name"`
+	Params []*Param `json:"params,omitempty"`
+	Return *Type    `json:"return,omitempty"` // nil if the method returns nothing
+}
+
+//Param describes a param of method
+type Param struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Type  *Type  `json:"type"`
+}
+
+//Config describes a group of options
+type Config struct {
+	Name    string            `json:"name"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+//MotanConfig is the motan rpc config of a schema, each map is keyed by the config name
+type MotanConfig struct {
+	Registries    map[string]map[string]string `json:"registries,omitempty"`
+	Protocols     map[string]map[string]string `json:"protocols,omitempty"`
+	BasicServices map[string]map[string]string `json:"basic_services,omitempty"`
+	BasicReferers map[string]map[string]string `json:"basic_referers,omitempty"`
+	Services      map[string]map[string]string `json:"services,omitempty"`
+	Referers      map[string]map[string]string `json:"referers,omitempty"`
+	ServiceImpls  map[string]map[string]string `json:"service_impls,omitempty"`
+}
+
+//Marshal encodes a schema set as indented json
+func Marshal(set *SchemaSet) ([]byte, error) {
+	content, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+//FromContext describes all schemas in context
+func FromContext(context *core.Context) *SchemaSet {
+	set := &SchemaSet{Version: Version, Schemas: make([]*Schema, 0, len(context.Schemas))}
+	for _, schema := range context.SortedSchemas() {
+		set.Schemas = append(set.Schemas, FromSchema(schema, context))
+	}
+	return set
+}
+
+//FromSchema describes a schema, message types are resolved by the messages in context
+func FromSchema(schema *core.Schema, context *core.Context) *Schema {
+	s := &Schema{Name: schema.Name, Package: schema.Package, Hash: schema.Hash, Options: copyOptions(schema.Options)}
+	for _, name := range sortedKeys(schema.Messages) {
+		message := schema.Messages[name]
+		m := &Message{Name: message.Name, FullName: schema.Package + "." + message.Name, Alias: message.Alias, Enum: message.IsEnum, Options: copyOptions(message.Options)}
+		if message.IsEnum {
+			numbers := make([]int, 0, len(message.EnumValues))
+			for number := range message.EnumValues {
+				numbers = append(numbers, number)
+			}
+			sort.Ints(numbers)
+			for _, number := range numbers {
+				m.EnumValues = append(m.EnumValues, &EnumValue{Number: number, Name: message.EnumValues[number]})
+			}
+		} else {
+			indexes := make([]int, 0, len(message.Fields))
+			for index := range message.Fields {
+				indexes = append(indexes, index)
+			}
+			sort.Ints(indexes)
+			for _, index := range indexes {
+				field := message.Fields[index]
+				m.Fields = append(m.Fields, &Field{Index: field.Index, Name: field.Name, Type: FromType(field.Type, schema, context), Options: copyOptions(field.Options)})
+			}
+		}
+		s.Messages = append(s.Messages, m)
+	}
+	for _, name := range sortedKeys(schema.Services) {
+		service := schema.Services[name]
+		sv := &Service{Name: service.Name, FullName: schema.Package + "." + service.Name, Options: copyOptions(service.Options)}
+		for _, methodName := range sortedKeys(service.Methods) {
+			method := service.Methods[methodName]
+			m := &Method{Name: method.Name}
+			indexes := make([]int, 0, len(method.Params))
+			for index := range method.Params {
+				indexes = append(indexes, index)
+			}
+			sort.Ints(indexes)
+			for _, index := range indexes {
+				param := method.Params[index]
+				m.Params = append(m.Params, &Param{Index: index, Name: param.Name, Type: FromType(param.Type, schema, context)})
+			}
+			if method.Return != nil {
+				m.Return = FromType(method.Return, schema, context)
+			}
+			sv.Methods = append(sv.Methods, m)
+		}
+		s.Services = append(s.Services, sv)
+	}
+	for _, name := range sortedKeys(schema.Configs) {
+		s.Configs = append(s.Configs, &Config{Name: name, Options: copyOptions(schema.Configs[name].Options)})
+	}
+	if mc := schema.MotanConfig; mc != nil {
+		s.Motan = &MotanConfig{Registries: mc.Registries, Protocols: mc.Protocols, BasicServices: mc.BasicServices, BasicReferers: mc.BasicReferers,
+			Services: mc.Services, Referers: mc.Referers, ServiceImpls: mc.ServiceImpls}
+	}
+	return s
+}
+
+//FromType describes a type. message names without package are resolved by the package of schema
+func FromType(tp *core.Type, schema *core.Schema, context *core.Context) *Type {
+	if tp == nil {
+		return nil
+	}
+	t := &Type{Kind: kinds[tp.Number]}
+	switch tp.Number {
+	case core.Map:
+		t.Key = FromType(tp.KeyType, schema, context)
+		t.Value = FromType(tp.ValueType, schema, context)
+	case core.Array:
+		t.Value = FromType(tp.ValueType, schema, context)
+	case core.Msg:
+		t.Name = tp.Name
+		if !strings.Contains(t.Name, ".") {
+			t.Name = schema.Package + "." + t.Name
+		}
+		if message := context.GetMessage(t.Name); message != nil && message.IsEnum {
+			t.Kind = KindEnum
+		}
+	}
+	return t
+}
+
+func copyOptions(options map[string]string) map[string]string {
+	if len(options) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(options))
+	for k, v := range options {
+		result[k] = v
+	}
+	return result
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch v := m.(type) {
+	case map[string]*core.Message:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]*core.Service:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]*core.Method:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]*core.Config:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,156 @@
+package generator
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+// maxDirDepth limits the depth of directories, in case of symlink loops which can not be detected by os.SameFile
+const maxDirDepth = 64
+
+// GenerateFS find all schema files in fsys, and generate code according config.
+// fsys can be any fs.FS, such as os.DirFS, embed.FS, zip.Reader or fstest.MapFS.
+func GenerateFS(fsys fs.FS, config *Config) ([]string, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	config.WriteFile = true // write to file
+	context, err := initContext(config)
+	if err != nil {
+		return nil, err
+	}
+	err = parseSchemaWithFS(fsys, config, context)
+	if err != nil {
+		return nil, err
+	}
+	return generateSchemas(context, config)
+}
+
+func parseSchemaWithFS(fsys fs.FS, config *Config, context *core.Context) error {
+	filter := &fileFilter{includes: config.Includes, excludes: config.Excludes}
+	files := findSchemaFilesFS(fsys, ".", context.Parser.FileSuffix(), filter, nil, 0, nil)
+	schemas := make([]*core.Schema, len(files))
+	errs := make([]error, len(files))
+	runParallel(context.Concurrency, len(files), func(index int) {
+		content, err := fs.ReadFile(fsys, files[index])
+		if err == nil {
+			schemas[index], err = buildSchema(path.Base(files[index]), content, context)
+		}
+		errs[index] = err
+	})
+	// add schemas in file order, so the result is same as sequential parsing
+	for index, schema := range schemas {
+		if errs[index] != nil {
+			fmt.Printf("warning: process file fail: %s, err:%s\n", files[index], errs[index])
+			continue
+		}
+		context.AddSchema(schema)
+	}
+	return nil
+}
+
+// findSchemaFilesFS find all schema files in dir of fsys recursively, in lexical order.
+// hidden directories are skipped, and symlinks to directories are followed only once.
+func findSchemaFilesFS(fsys fs.FS, dir string, suffix string, filter *fileFilter, visited []fs.FileInfo, depth int, files []string) []string {
+	if depth > maxDirDepth {
+		fmt.Printf("warning: directory is too deep, maybe there is a symlink loop: %s\n", dir)
+		return files
+	}
+	if info, err := fs.Stat(fsys, dir); err == nil {
+		for _, v := range visited {
+			if os.SameFile(v, info) { // symlink loop or a directory linked twice
+				return files
+			}
+		}
+		visited = append(visited, info)
+	}
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		fmt.Printf("warning: read dir fail: %s, err:%s\n", dir, err)
+		return files
+	}
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		isDir := entry.IsDir()
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if info, err := fs.Stat(fsys, name); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if isDir {
+			if strings.HasPrefix(entry.Name(), ".") || filter.excluded(name) {
+				continue
+			}
+			files = findSchemaFilesFS(fsys, name, suffix, filter, visited, depth+1, files)
+		} else if strings.HasSuffix(entry.Name(), suffix) && filter.included(name) {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// fileFilter filters schema files by glob patterns.
+// a pattern without '/' matches the base name of a file, otherwise matches the slash separated path relative to the root.
+// '**' in a pattern matches zero or more directories.
+type fileFilter struct {
+	includes []string
+	excludes []string
+}
+
+func (f *fileFilter) included(name string) bool {
+	if f.excluded(name) {
+		return false
+	}
+	if len(f.includes) == 0 {
+		return true
+	}
+	return matchAny(f.includes, name)
+}
+
+func (f *fileFilter) excluded(name string) bool {
+	return matchAny(f.excludes, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlob(pattern string, name string) bool {
+	pattern = strings.Trim(pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(name))
+		return matched
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(patterns []string, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(patterns[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(patterns[0], names[0]); !matched {
+			return false
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0
+}
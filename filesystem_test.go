@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	assert2 "github.com/stretchr/testify/assert"
+	"github.com/weibreeze/breeze-generator/outputs"
+)
+
+func TestGenerateFS(t *testing.T) {
+	assert := assert2.New(t)
+	schema := func(pkg string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte("package " + pkg + ";\nmessage M {\n    int32 id = 1;\n}\n")}
+	}
+	fsys := fstest.MapFS{
+		"a.breeze":              schema("a"),
+		"sub/b.breeze":          schema("b"),
+		"sub/deep/c.breeze":     schema("c"),
+		"sub/deep/c.proto":      schema("c"),
+		".hidden/d.breeze":      schema("d"),
+		"vendor/e.breeze":       schema("e"),
+		"sub/deep/f_old.breeze": schema("f"),
+	}
+	config := &Config{CodeTemplates: "go", Output: outputs.NewMemoryOutput(), Excludes: []string{"vendor", "*_old.breeze"}}
+	names, err := GenerateFS(fsys, config)
+	assert.Nil(err)
+	assert.Equal([]string{"a.breeze", "b.breeze", "c.breeze"}, names)
+
+	config = &Config{CodeTemplates: "go", Output: outputs.NewMemoryOutput(), Includes: []string{"sub/**/*.breeze"}, Excludes: []string{"sub/deep/f_*"}}
+	names, err = GenerateFS(fsys, config)
+	assert.Nil(err)
+	assert.Equal([]string{"b.breeze", "c.breeze"}, names)
+
+	// symlink loop
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "sub", "a.breeze"), schema("a").Data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if os.Symlink(src, filepath.Join(src, "sub", "loop")) == nil {
+		names, err = GeneratePath(src, &Config{CodeTemplates: "go", Output: outputs.NewMemoryOutput()})
+		assert.Nil(err)
+		assert.Equal([]string{"a.breeze"}, names)
+	}
+}
@@ -1,6 +1,8 @@
 package generator
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/weibreeze/breeze-generator/motan"
@@ -8,23 +10,37 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
-	"syscall"
 
 	"github.com/weibreeze/breeze-generator/core"
+	"github.com/weibreeze/breeze-generator/outputs"
 	"github.com/weibreeze/breeze-generator/parsers"
+	"github.com/weibreeze/breeze-generator/plugin"
 	"github.com/weibreeze/breeze-generator/templates"
 )
 
 //Config is a generate config struct
 type Config struct {
-	WriteFile     bool
-	Parser        string
-	CodeTemplates string
-	WritePath     string
-	Options       map[string]string
+	WriteFile      bool
+	Parser         string
+	CodeTemplates  string
+	WritePath      string
+	Options        map[string]string
+	Force          bool              // overwrite files which are not generated by breeze-generator
+	Concurrency    int               // max goroutines for parsing schemas and generating code, runtime.NumCPU() is used if not set
+	UniformPackage string            // if not empty, used as the package of all schemas, so all classes will be in same package
+	Output         core.Output       // where generated files are written to, a outputs.DirOutput of WritePath is used if not set
+	Includes       []string          // glob patterns of schema files to include, all schema files are included if empty
+	Excludes       []string          // glob patterns of schema files or directories to exclude
+	Cache          *Cache            // only regenerate the schemas which changed if not nil
+	TemplatePaths  map[string]string // output directory of each template, <WritePath>/<template name> is used if not set
+	Plugins        []string          // names or paths of external generator plugins, only plugins are used if CodeTemplates is empty
+	TextTemplates  []string          // directories of templates.TextTemplate, they are used with CodeTemplates and Plugins
 }
 
+const motanConfigDir = "motanConfig"
+
 //RegisterParser can register a custom Parser for extension
 func RegisterParser(parser core.Parser) {
 	parsers.Register(parser)
@@ -37,37 +53,65 @@ func RegisterCodeTemplate(template core.CodeTemplate) {
 
 //GeneratePath find all schema files in path, and generate code according config
 func GeneratePath(path string, config *Config) ([]string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	_, err = f.Stat()
-	if err != nil {
-		return nil, err
+	return GeneratePaths([]string{path}, config)
+}
+
+//GeneratePaths find all schema files in paths, and generate code of them together according config.
+//a path can be a directory or a schema file. if config.WritePath is empty, the first path is used.
+func GeneratePaths(paths []string, config *Config) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no schema path")
 	}
 	if config == nil {
 		config = &Config{}
 	}
 	if config.WritePath == "" {
-		config.WritePath = path
+		config.WritePath = paths[0]
 	}
 	config.WriteFile = true // write to file
 	context, err := initContext(config)
 	if err != nil {
 		return nil, err
 	}
-	err = parseSchemaWithPath(path, context)
+	if err = parsePaths(paths, config, context); err != nil {
+		return nil, err
+	}
+	return generateSchemas(context, config)
+}
+
+// parsePaths parses all schema files in paths into context, a path can be a directory or a schema file.
+func parsePaths(paths []string, config *Config, context *core.Context) error {
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			err = parseSchemaWithFS(os.DirFS(path), config, context)
+		} else {
+			err = parseSchemaWithPath(path, context)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateSchemas generates code of all schemas in context into the output of config, returns the schema names.
+func generateSchemas(context *core.Context, config *Config) ([]string, error) {
+	err := initOutput(context, config, true)
 	if err != nil {
 		return nil, err
 	}
-	err = generateCode(context)
+	err = generateCode(context, config.Cache)
 	if err != nil {
 		return nil, err
 	}
 
 	fileNames := make([]string, 0, len(context.Schemas))
-	for key := range context.Schemas {
-		fileNames = append(fileNames, key)
+	for _, schema := range context.SortedSchemas() {
+		fileNames = append(fileNames, schema.Name)
 	}
 	return fileNames, nil
 }
@@ -83,7 +127,11 @@ func Generate(name string, content []byte, config *Config) error {
 	if err != nil {
 		return err
 	}
-	return generateCode(context)
+	err = initOutput(context, config, false)
+	if err != nil {
+		return err
+	}
+	return generateCode(context, config.Cache)
 }
 
 // GeneratByFileContent 接受多个文件的字符内容进行生成代码，生成后的代码也同样使用字符内容来返回
@@ -92,74 +140,91 @@ func GeneratByFileContent(files map[string]string, config *Config) (map[string]s
 	if err != nil {
 		return nil, nil, err
 	}
-	for name, content := range files {
-		err = parseSchema(name, []byte(content), context)
-		if err != nil {
-			return nil, nil, err
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	schemas := make([]*core.Schema, len(names))
+	errs := make([]error, len(names))
+	runParallel(context.Concurrency, len(names), func(index int) {
+		schemas[index], errs[index] = buildSchema(names[index], []byte(files[names[index]]), context)
+	})
+	for index, schema := range schemas {
+		if errs[index] != nil {
+			return nil, nil, errs[index]
 		}
+		context.AddSchema(schema)
 	}
-	return generateCodeFileContent(context)
-}
-
-func parseSchemaWithPath(path string, context *core.Context) error {
-	f, _ := os.Open(path)
-	fi, err := f.Stat()
+	output := outputs.NewMemoryOutput()
+	context.Output = output
+	err = generateCode(context, config.Cache)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-
-	if fi.IsDir() {
-		var fileInfo []os.FileInfo
-		fileInfo, err = ioutil.ReadDir(path)
-		if err == nil {
-			path = addSeparator(path)
-			for _, info := range fileInfo {
-				subName := path + info.Name()
-				errForLog := parseSchemaWithPath(subName, context)
-				if errForLog != nil {
-					fmt.Printf("warning: process file fail: %s, err:%s\n", subName, errForLog)
-					continue
-				}
+	codeFiles := make(map[string]string)
+	configFiles := make(map[string]string)
+	for template, files := range output.Files {
+		for name, content := range files {
+			if template == motanConfigDir {
+				configFiles[name] = string(content)
+			} else {
+				codeFiles[name] = string(content)
 			}
 		}
-	} else if strings.HasSuffix(fi.Name(), context.Parser.FileSuffix()) {
-		var content []byte
-		content, err = ioutil.ReadFile(path)
-		if err == nil {
-			err = parseSchema(fi.Name(), content, context)
-		}
 	}
-	return err
+	return codeFiles, configFiles, nil
 }
 
-func parseSchema(name string, content []byte, context *core.Context) error {
-	schema, err := context.Parser.ParseSchema(content, context)
+// parseSchemaWithPath parses a single schema file
+func parseSchemaWithPath(path string, context *core.Context) error {
+	fi, err := os.Stat(path)
 	if err != nil {
 		return err
 	}
-	schema.Name = name
-	err = core.Validate(schema)
+	if fi.IsDir() || !strings.HasSuffix(fi.Name(), context.Parser.FileSuffix()) {
+		return nil
+	}
+	content, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	// merge option from context
-	mergeOptions(schema.Options, context.Options)
+	return parseSchema(fi.Name(), content, context)
+}
 
-	// build motan config
-	err = motan.BuildMotanConfig(schema)
+func parseSchema(name string, content []byte, context *core.Context) error {
+	schema, err := buildSchema(name, content, context)
 	if err != nil {
 		return err
 	}
-
 	//add schemas and messages to context
-	context.Schemas[schema.Name] = schema
-	for key, value := range schema.Messages {
-		context.Messages[schema.Package+"."+key] = value
-		mergeOptions(value.Options, schema.Options)
-	}
+	context.AddSchema(schema)
 	return nil
 }
 
+// buildSchema parse and complete a schema without modifying context, so it can be called concurrently.
+func buildSchema(name string, content []byte, context *core.Context) (*core.Schema, error) {
+	schema, err := context.Parser.ParseSchema(content, context)
+	if err != nil {
+		return nil, err
+	}
+	schema.Name = name
+	sum := sha256.Sum256(content)
+	schema.Hash = hex.EncodeToString(sum[:])
+	err = core.Validate(schema)
+	if err != nil {
+		return nil, err
+	}
+	// build motan config. options of context are merged into schema when generating, because each template has its own options
+	merged := mergedSchema(schema, motanOptions(context))
+	err = motan.BuildMotanConfig(merged)
+	if err != nil {
+		return nil, err
+	}
+	schema.MotanConfig = merged.MotanConfig
+	return schema, nil
+}
+
 func mergeOptions(toOption map[string]string, fromOption map[string]string) {
 	for k, v := range fromOption {
 		if toOption[k] == "" {
@@ -168,86 +233,153 @@ func mergeOptions(toOption map[string]string, fromOption map[string]string) {
 	}
 }
 
-func generateCodeFileContent(context *core.Context) (map[string]string, map[string]string, error) {
-	codeFiles := make(map[string]string)
-	configFiles := make(map[string]string)
-	for _, schema := range context.Schemas {
-		// generate code file
+// generateResult is the result of generating code of a schema by a template
+type generateResult struct {
+	schema   *core.Schema
+	template core.CodeTemplate
+	files    map[string][]byte
+	err      error
+}
+
+// generateAll generates code for every schema and template concurrently.
+// each template generates code with a view of context which only contains its options.
+// results are ordered by schema name and template order, so the output is deterministic.
+// if cache is not nil, only the schemas whose cache key changes will be generated.
+func generateAll(context *core.Context, cache *Cache) []*generateResult {
+	views := make(map[string]*core.Context, len(context.Templates))
+	keys := make(map[string]map[string]string, len(context.Templates))
+	for _, template := range context.Templates {
+		views[template.Name()] = optionView(context, templateOptions(context, template))
+		if cache != nil {
+			keys[template.Name()] = cacheKeys(views[template.Name()], template)
+		}
+	}
+	schemas := context.SortedSchemas()
+	results := make([]*generateResult, 0, len(schemas)*len(context.Templates))
+	for _, schema := range schemas {
 		for _, template := range context.Templates {
-			files, err := template.GenerateCode(schema, context)
-			if err != nil {
-				return nil, nil, err
-			}
-			for name, bytes := range files {
-				codeFiles[name] = string(bytes)
-			}
+			results = append(results, &generateResult{schema: views[template.Name()].GetSchema(schema.Name), template: template})
 		}
-		// generate config file
-		if schema.Options[core.WithMotanConfig] == "true" {
-			files, err := motan.GenerateConfig(schema)
-			if err != nil {
-				return nil, nil, err
-			}
-			for name, bytes := range files {
-				configFiles[name] = string(bytes)
+	}
+	runParallel(context.Concurrency, len(results), func(index int) {
+		result := results[index]
+		var name, key string
+		if cache != nil {
+			name = result.template.Name() + ":" + result.schema.Name
+			key = keys[result.template.Name()][result.schema.Name]
+			if result.files = cache.get(name, key); result.files != nil {
+				return
 			}
 		}
-	}
-	return codeFiles, configFiles, nil
+		result.files, result.err = result.template.GenerateCode(result.schema, views[result.template.Name()])
+		if cache != nil && result.err == nil {
+			cache.put(name, key, result.files)
+		}
+	})
+	return results
 }
 
-func generateCode(context *core.Context) error {
-	oldMask := syscall.Umask(0)
-	defer syscall.Umask(oldMask)
-	for _, schema := range context.Schemas {
-		basePath := context.WritePath
-		if !strings.HasSuffix(basePath, string(os.PathSeparator)) {
-			basePath += string(os.PathSeparator)
-		}
-		for _, template := range context.Templates {
-			files, err := template.GenerateCode(schema, context)
-			if err != nil {
-				fmt.Printf("error: generate code fail, template:%s, err:%s\n", template.Name(), err.Error())
-				continue
+// generateCode generates code of all schemas in context, and writes generated files into context.Output.
+// the first error of generating or writing is returned after all other files are written.
+func generateCode(context *core.Context, cache *Cache) error {
+	var generateErr error
+	for _, result := range generateAll(context, cache) {
+		template, schema := result.template, result.schema
+		if result.err != nil {
+			fmt.Printf("error: generate code fail, template:%s, err:%s\n", template.Name(), result.err.Error())
+			if r, ok := context.Output.(retainer); ok {
+				r.Retain(template.Name(), schema.Name)
 			}
-			path := basePath + template.Name() + string(os.PathSeparator)
-			err = os.MkdirAll(path, 0777)
-			if err != nil {
-				return err
-			}
-			for name, content := range files {
-				index := strings.LastIndex(name, string(os.PathSeparator)) //contains path
-				if index > -1 {
-					err := os.MkdirAll(path+name[:index+1], 0777)
-					if err != nil {
-						return err
-					}
-				}
-				err = ioutil.WriteFile(path+name, content, 0666)
-				if err != nil {
-					fmt.Printf("error: write code fail, template:%s, file name:%s, err:%s\n", template.Name(), name, err.Error())
-				}
+			if generateErr == nil {
+				generateErr = result.err
 			}
+			continue
 		}
+		if err := writeFiles(context, template.Name(), schema.Name, result.files); err != nil && generateErr == nil {
+			generateErr = err
+		}
+	}
+	options := motanOptions(context)
+	for _, schema := range context.SortedSchemas() {
 		// generate motan config
+		schema = mergedSchema(schema, options)
 		if schema.Options[core.WithMotanConfig] == "true" {
 			files, err := motan.GenerateConfig(schema)
 			if err != nil {
-				return err
+				if r, ok := context.Output.(retainer); ok {
+					r.Retain(motanConfigDir, schema.Name)
+				}
+				if generateErr == nil {
+					generateErr = err
+				}
+				continue
 			}
-			configPath := basePath + "motanConfig" + string(os.PathSeparator)
-			err = os.MkdirAll(configPath, 0777)
-			if err != nil {
-				return err
+			if err = writeFiles(context, motanConfigDir, schema.Name, files); err != nil && generateErr == nil {
+				generateErr = err
 			}
-			for name, content := range files {
-				err = ioutil.WriteFile(configPath+name, content, 0666)
-				if err != nil {
-					fmt.Printf("error: write motan config fail, file name:%s, err:%s\n", name, err.Error())
-				}
+		}
+	}
+	err := context.Output.Close()
+	if generateErr != nil {
+		return generateErr
+	}
+	if err == nil && cache != nil {
+		err = cache.Save()
+	}
+	return err
+}
+
+// retainer is implemented by outputs which can keep files generated before, such as outputs.DirOutput
+type retainer interface {
+	Retain(template string, schema string)
+}
+
+// writeFiles writes generated files of schema into context.Output, the first error is returned after all other files are written.
+// files generated before from the schema are retained if any file fails, so they will not be deleted as orphan files.
+func writeFiles(context *core.Context, template string, schema string, files map[string][]byte) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var writeErr error
+	for _, name := range names {
+		err := context.Output.Write(&core.GeneratedFile{Template: template, Schema: schema, Name: name, Content: files[name]})
+		if err != nil {
+			fmt.Printf("error: write file fail, template:%s, file name:%s, err:%s\n", template, name, err.Error())
+			if writeErr == nil {
+				writeErr = err
 			}
 		}
 	}
+	if writeErr != nil {
+		if r, ok := context.Output.(retainer); ok {
+			r.Retain(template, schema)
+		}
+	}
+	return writeErr
+}
+
+// initOutput set the output of context by config. if config.Output is nil, a DirOutput of write path is used.
+// if fullSet is true, the schemas in context are treated as all schemas of write path, so generated files of removed schemas will be deleted.
+func initOutput(context *core.Context, config *Config, fullSet bool) error {
+	if config.Output != nil {
+		context.Output = config.Output
+		return nil
+	}
+	output, err := outputs.NewDirOutput(context.WritePath, config.Force)
+	if err != nil {
+		return err
+	}
+	output.Roots = config.TemplatePaths
+	templateNames := map[string]bool{motanConfigDir: true}
+	for _, template := range context.Templates {
+		templateNames[template.Name()] = true
+	}
+	output.Keep = func(template string, schema string) bool {
+		return !templateNames[template] || (!fullSet && context.GetSchema(schema) == nil)
+	}
+	context.Output = output
 	return nil
 }
 
@@ -258,25 +390,42 @@ func initContext(config *Config) (*core.Context, error) {
 	if config.Parser == "" {
 		config.Parser = parsers.Breeze
 	}
-	if config.CodeTemplates == "" {
+	if config.CodeTemplates == "" && len(config.Plugins) == 0 && len(config.TextTemplates) == 0 {
 		config.CodeTemplates = templates.All
 	}
 	if config.WritePath == "" {
 		config.WritePath = "./"
 	}
 	config.WritePath = addSeparator(config.WritePath)
-	context := &core.Context{Parser: parsers.GetParser(config.Parser), Schemas: make(map[string]*core.Schema), Messages: make(map[string]*core.Message), WritePath: config.WritePath}
-	if config.Options != nil {
-		context.Options = config.Options
-	} else {
-		context.Options = make(map[string]string)
-	}
+	context := &core.Context{Parser: parsers.GetParser(config.Parser), Schemas: make(map[string]*core.Schema), Messages: make(map[string]*core.Message), WritePath: config.WritePath, Concurrency: config.Concurrency, UniformPackage: config.UniformPackage}
 	if context.Parser == nil {
 		return nil, errors.New("can not find parser: " + config.Parser)
 	}
-	var err error
-	context.Templates, err = templates.GetTemplate(config.CodeTemplates)
-	return context, err
+	if config.CodeTemplates != "" {
+		var err error
+		if context.Templates, err = templates.GetTemplate(config.CodeTemplates); err != nil {
+			return nil, err
+		}
+	}
+	for _, dir := range config.TextTemplates {
+		t, err := templates.LoadTextTemplate(dir)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := templates.GetTemplate(t.Name()); err == nil {
+			return nil, errors.New("text template name conflicts with template: " + t.Name())
+		}
+		context.Templates = append(context.Templates, t)
+	}
+	for _, name := range config.Plugins {
+		p := plugin.New(name)
+		if _, err := templates.GetTemplate(p.Name()); err == nil {
+			return nil, errors.New("plugin name conflicts with template: " + p.Name())
+		}
+		context.Templates = append(context.Templates, p)
+	}
+	context.Options, context.TemplateOptions = resolveOptions(config.Options, context.Templates)
+	return context, nil
 }
 
 func addSeparator(path string) string {
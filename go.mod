@@ -1,6 +1,6 @@
 module github.com/weibreeze/breeze-generator
 
-go 1.12
+go 1.16
 
 require (
 	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751 // indirect
@@ -8,4 +8,5 @@ require (
 	github.com/stretchr/testify v1.6.1
 	github.com/weibreeze/breeze-go v0.1.0
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -23,6 +23,7 @@ func (s *GenerateCodeHandler) ServeHTTP(rw http.ResponseWriter, req *http.Reques
 	res := &GenerateRes{}
 	targetLanguage := req.FormValue("target_language")
 	optionsStr := req.FormValue("options")
+	uniformPackage := req.FormValue("uniform_package")
 	fileContentStr := req.FormValue("file_content")
 	if fileContentStr != "" {
 		fileMap := make(map[string]string)
@@ -34,7 +35,7 @@ func (s *GenerateCodeHandler) ServeHTTP(rw http.ResponseWriter, req *http.Reques
 		if optionsMap[core.WithPackageDir] == "" {
 			optionsMap[core.WithPackageDir] = "true"
 		}
-		config := &generator.Config{WriteFile: false, CodeTemplates: targetLanguage, Options: optionsMap}
+		config := &generator.Config{WriteFile: false, CodeTemplates: targetLanguage, Options: optionsMap, UniformPackage: uniformPackage}
 		var err error
 		res.CodeContent, res.ConfigContent, err = generator.GeneratByFileContent(fileMap, config)
 		if err == nil {
@@ -52,7 +53,7 @@ func (s *GenerateCodeHandler) ServeHTTP(rw http.ResponseWriter, req *http.Reques
 		rw.Write(bytes)
 		return
 	} else {
-		fmt.Errorf("GenerateCodeHandler: encode json fail. err: {%s}", err.Error())
+		fmt.Printf("GenerateCodeHandler: encode json fail. err: {%s}\n", err.Error())
 		rw.Write([]byte("{\"result\":false,\"err_msg\":\"encode json fail." + err.Error() + "\"}"))
 	}
 }
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+const (
+	testSchema = `
+package test.user;
+
+message User {
+    int32 uid = 1;
+    test.common.Address address = 2;
+}
+`
+	testCommonSchema = `
+package test.common;
+
+message Address {
+    string city = 1;
+}
+`
+)
+
+func TestConcurrentGenerateCodeHandler(t *testing.T) {
+	assert := assert2.New(t)
+	server := httptest.NewServer(&GenerateCodeHandler{})
+	defer server.Close()
+	files, _ := json.Marshal(map[string]string{"test.user.breeze": testSchema, "test.common.breeze": testCommonSchema})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := strconv.Itoa(i)
+			uniform := i%2 == 0
+			options, _ := json.Marshal(map[string]string{"java_package": "com.test" + id, "go_package_prefix": "github.com/test" + id + "/"})
+			form := url.Values{"target_language": {"java,go"}, "file_content": {string(files)}, "options": {string(options)}}
+			if uniform {
+				form.Set("uniform_package", "uniform"+id)
+			}
+			resp, err := http.PostForm(server.URL, form)
+			if !assert.Nil(err) {
+				return
+			}
+			defer resp.Body.Close()
+			res := &GenerateRes{}
+			assert.Nil(json.NewDecoder(resp.Body).Decode(res))
+			assert.True(res.Result, res.ErrMsg)
+			for name, content := range res.CodeContent {
+				if strings.HasSuffix(name, ".java") {
+					assert.Contains(content, "package com.test"+id+";")
+					continue
+				}
+				if uniform {
+					assert.True(strings.HasPrefix(name, "uniform"+id), name)
+					assert.Contains(content, "package uniform"+id+"\n")
+					assert.NotContains(content, "github.com/test")
+				} else if strings.Contains(name, "user") {
+					assert.Contains(content, "github.com/test"+id+"/test/common")
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
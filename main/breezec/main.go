@@ -4,10 +4,27 @@ import (
 	"fmt"
 	generator "github.com/weibreeze/breeze-generator"
 	"github.com/weibreeze/breeze-generator/core"
+	"github.com/weibreeze/breeze-generator/descriptor"
 	"gopkg.in/alecthomas/kingpin.v2"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"strings"
 )
 
+// loadProject loads the project file of path, or the project file found in current directory and its parents.
+// an empty project is returned if no project file is found.
+func loadProject(path string) (*generator.Project, error) {
+	if path == "" {
+		var err error
+		if path, err = generator.FindProject("."); err != nil || path == "" {
+			return &generator.Project{}, err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "use project file: %s\n", path)
+	return generator.LoadProject(path)
+}
+
 func main() {
 	defer func() {
 		if e:=recover();e!=nil{
@@ -17,10 +34,24 @@ func main() {
 	app := kingpin.New("breeze-generator", "toolchain for breeze, https://github.com/weibreeze/breeze/")
 
 	genCMD := app.Command("gen", "")
-	gen_typ := genCMD.Flag("type", "generator code type: go, php, java, cpp").Default("all").String()
+	gen_config := genCMD.Flag("config", "project file, breeze.yaml, breeze.yml or breeze.json in current directory or its parents is used if not set").Default("").String()
+	gen_typ := genCMD.Flag("type", "generator code type: go, php, java, cpp. default is all").Default("").String()
 	gen_src := genCMD.Flag("src", "source path of files .breeze").Default("").String()
-	gen_dest := genCMD.Flag("dest", "destination path of generated files").Default("autoGenerate").String()
+	gen_dest := genCMD.Flag("dest", "destination path of generated files. default is autoGenerate").Default("").String()
+	gen_plugins := genCMD.Flag("plugin", "external generator plugin, executable breeze-gen-<name> in PATH or a path of executable. can be repeated").Strings()
+	gen_text_templates := genCMD.Flag("template-dir", "directory of text template files, can be repeated").Strings()
 	gen_go_pkg := genCMD.Flag("gopkg", "prefix of go import package").Default("").String()
+	gen_force := genCMD.Flag("force", "overwrite files which are not generated by breeze-generator").Bool()
+	gen_cache := genCMD.Flag("cache", "cache file for incremental generation, only changed schemas will be regenerated").Default("").String()
+	gen_invalidate_cache := genCMD.Flag("invalidate-cache", "ignore the cache and regenerate all schemas").Bool()
+	gen_watch := genCMD.Flag("watch", "keep watching the source path and regenerate changed schemas").Bool()
+	gen_interval := genCMD.Flag("interval", "polling interval of watch mode").Default("1s").Duration()
+
+	describeCMD := app.Command("describe", "describe parsed schemas and effective options as json or binary descriptor set")
+	describe_config := describeCMD.Flag("config", "project file, breeze.yaml, breeze.yml or breeze.json in current directory or its parents is used if not set").Default("").String()
+	describe_src := describeCMD.Flag("src", "source path of files .breeze").Default("").String()
+	describe_out := describeCMD.Flag("out", "output file, default is stdout").Default("").String()
+	describe_format := describeCMD.Flag("format", "output format: json, or binary which is a descriptor set encoded by breeze").Default("json").Enum("json", "binary")
 
 	p2bCMD := app.Command("p2b", "convert files .proto to .breeze, rule details: https://github.com/weibreeze/breeze/")
 	p2b_src := p2bCMD.Flag("src", "source path of files .proto").Default("").String()
@@ -37,15 +68,126 @@ func main() {
 	}
 	switch command {
 	case "gen":
-		config := &generator.Config{WritePath: *gen_dest, CodeTemplates: *gen_typ, Options: make(map[string]string)}
-		config.Options[core.WithPackageDir] = "true"
+		project, err := loadProject(*gen_config)
+		if err != nil {
+			fmt.Printf("load project fail, error: %s\n", err)
+			return
+		}
+		// command line flags take precedence over the project file
+		if *gen_src != "" {
+			project.Sources = []string{*gen_src}
+		}
+		if *gen_typ != "" && *gen_typ != "all" {
+			project.Templates = strings.Split(*gen_typ, ",")
+		}
+		if len(*gen_plugins) > 0 {
+			project.Plugins = *gen_plugins
+		}
+		if len(*gen_text_templates) > 0 {
+			project.TextTemplates = *gen_text_templates
+		}
+		if *gen_dest != "" {
+			project.SetOutput(*gen_dest)
+		} else if project.Output == "" {
+			project.Output = "autoGenerate"
+		}
+		project.SetDefaultOption(core.WithPackageDir, "true")
 		if *gen_go_pkg != "" {
-			config.Options[core.GoPackagePrefix] = *gen_go_pkg
+			project.SetOption(core.GoPackagePrefix, *gen_go_pkg)
+		}
+		if *gen_force {
+			project.Force = true
+		}
+		if *gen_cache != "" {
+			project.Cache = *gen_cache
+		}
+		if len(project.Sources) == 0 {
+			fmt.Println("no source path, use --src or sources in project file")
+			return
+		}
+		config, err := project.Config()
+		if err != nil {
+			fmt.Printf("generator fail, error: %s\n", err)
+			return
+		}
+		if config.Cache != nil && *gen_invalidate_cache {
+			config.Cache.Invalidate()
 		}
-		_, err = generator.GeneratePath(*gen_src, config)
+		if *gen_watch {
+			if len(project.Sources) != 1 {
+				fmt.Println("watch mode supports only one source path")
+				return
+			}
+			watcher, err := generator.NewWatcher(project.Sources[0], config)
+			if err != nil {
+				fmt.Printf("watch fail, error: %s\n", err)
+				return
+			}
+			watcher.Interval = *gen_interval
+			stop := make(chan struct{})
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt)
+			go func() {
+				<-signals
+				close(stop)
+			}()
+			fmt.Printf("watching %s, interval: %s\n", project.Sources[0], watcher.Interval)
+			if err = watcher.Run(stop); err != nil {
+				fmt.Printf("watch fail, error: %s\n", err)
+			}
+			return
+		}
+		_, err = generator.GeneratePaths(project.Sources, config)
 		if err != nil {
 			fmt.Printf("generator fail, error: %s\n", err)
 		}
+		if config.Cache != nil {
+			hits, misses := config.Cache.Stats()
+			fmt.Printf("cache hits: %d, misses: %d\n", hits, misses)
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+	case "describe":
+		project, err := loadProject(*describe_config)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "load project fail, error: %s\n", err)
+			return
+		}
+		if *describe_src != "" {
+			project.Sources = []string{*describe_src}
+		}
+		if len(project.Sources) == 0 {
+			fmt.Fprintln(os.Stderr, "no source path, use --src or sources in project file")
+			return
+		}
+		if project.Output == "" {
+			project.Output = "autoGenerate" // not used, nothing is generated
+		}
+		config, err := project.Config()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "describe fail, error: %s\n", err)
+			return
+		}
+		set, err := generator.Describe(project.Sources, config)
+		if err == nil {
+			var content []byte
+			if *describe_format == "binary" {
+				content, err = descriptor.MarshalBinary(set)
+			} else {
+				content, err = descriptor.Marshal(set)
+			}
+			if err == nil {
+				if *describe_out == "" {
+					_, err = os.Stdout.Write(content)
+				} else {
+					err = ioutil.WriteFile(*describe_out, content, 0644)
+				}
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "describe fail, error: %s\n", err)
+		}
 	case "p2b":
 		if *p2b_src == "" || *p2b_dest == "" {
 			return
@@ -28,10 +28,10 @@ func testGenerateCode() {
 	flag.StringVar(&srcDir, "src", defaultPath, "breeze schema files path")
 	flag.StringVar(&goPkgPath, "gopkg", "", "project package path in $GOPATH")
 	flag.Parse()
-	//parsers.UniformPackage = "motan" // set UniformPackage if you want all class in same package.
 
 	path := srcDir
 	config := &generator.Config{WritePath: "./autoGenerate", CodeTemplates: "all", Options: make(map[string]string)}
+	//config.UniformPackage = "motan" // set UniformPackage if you want all class in same package.
 	config.Options[core.WithPackageDir] = "true"
 	config.Options[core.GoPackagePrefix] = goPkgPath
 
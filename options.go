@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/weibreeze/breeze-generator/core"
+	"github.com/weibreeze/breeze-generator/templates"
+)
+
+// resolveOptions splits options into the options for all templates and the namespaced options of each template.
+// a namespaced option is named as `<template>.<option>`, such as `java.package` and `go.package_prefix`.
+// the template of a namespace is found in templates of context and registered templates.
+func resolveOptions(options map[string]string, contextTemplates []core.CodeTemplate) (map[string]string, map[string]map[string]string) {
+	common := make(map[string]string, len(options))
+	templateOptions := make(map[string]map[string]string)
+	for k, v := range options {
+		index := strings.Index(k, ".")
+		if index <= 0 {
+			common[k] = v
+			continue
+		}
+		template := findTemplate(k[:index], contextTemplates)
+		if template == nil {
+			common[k] = v
+			continue
+		}
+		key, ok := optionKey(template, k[index+1:])
+		if !ok {
+			fmt.Printf("warning: option %s is not accepted by template %s\n", k, template.Name())
+			continue
+		}
+		if templateOptions[template.Name()] == nil {
+			templateOptions[template.Name()] = make(map[string]string)
+		}
+		templateOptions[template.Name()][key] = v
+	}
+	return common, templateOptions
+}
+
+func findTemplate(name string, contextTemplates []core.CodeTemplate) core.CodeTemplate {
+	for _, template := range contextTemplates {
+		if template.Name() == name {
+			return template
+		}
+	}
+	if ts, err := templates.GetTemplate(name); err == nil && len(ts) == 1 {
+		return ts[0]
+	}
+	return nil
+}
+
+// optionKey returns the option key of name in the namespace of template.
+// templates not implementing core.OptionTemplate accept any option.
+func optionKey(template core.CodeTemplate, name string) (string, bool) {
+	ot, ok := template.(core.OptionTemplate)
+	if !ok {
+		return name, true
+	}
+	for _, option := range ot.Options() {
+		if option.Name == name || option.Key == name {
+			return option.Key, true
+		}
+	}
+	return "", false
+}
+
+// templateOptions returns the options for template, includes common options it accepts and its namespaced options
+func templateOptions(context *core.Context, template core.CodeTemplate) map[string]string {
+	options := make(map[string]string, len(context.Options))
+	for k, v := range context.Options {
+		if key, ok := optionKey(template, k); ok && key == k {
+			options[k] = v
+		}
+	}
+	for k, v := range context.TemplateOptions[template.Name()] {
+		options[k] = v
+	}
+	return options
+}
+
+// motanOptions returns the options for building motan config.
+// the namespaced options of java are used too, because interfaces in motan config are java classes.
+func motanOptions(context *core.Context) map[string]string {
+	options := make(map[string]string, len(context.Options))
+	for k, v := range context.Options {
+		options[k] = v
+	}
+	mergeOptions(options, context.TemplateOptions[templates.Java])
+	return options
+}
+
+// optionView returns a copy of context whose options are replaced by options,
+// and options of schemas and messages are merged with them. context is not modified.
+func optionView(context *core.Context, options map[string]string) *core.Context {
+	view := &core.Context{WritePath: context.WritePath, Parser: context.Parser, RPCType: context.RPCType, Templates: context.Templates,
+		Options: options, TemplateOptions: context.TemplateOptions, Concurrency: context.Concurrency, UniformPackage: context.UniformPackage, Output: context.Output}
+	for _, schema := range context.SortedSchemas() {
+		view.AddSchema(mergedSchema(schema, options))
+	}
+	return view
+}
+
+// mergedSchema returns a copy of schema whose options are merged with options, options of schema take precedence.
+func mergedSchema(schema *core.Schema, options map[string]string) *core.Schema {
+	merged := *schema
+	merged.Options = make(map[string]string, len(schema.Options)+len(options))
+	mergeOptions(merged.Options, schema.Options)
+	mergeOptions(merged.Options, options)
+	merged.Messages = make(map[string]*core.Message, len(schema.Messages))
+	for name, message := range schema.Messages {
+		m := *message
+		m.Options = make(map[string]string, len(message.Options)+len(merged.Options))
+		mergeOptions(m.Options, message.Options)
+		mergeOptions(m.Options, merged.Options)
+		merged.Messages[name] = &m
+	}
+	return &merged
+}
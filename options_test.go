@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+func TestTemplateOptions(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{"a.breeze": "package test.a;\nmessage A {\n    int32 id = 1;\n}\n"}
+	options := map[string]string{core.WithPackageDir: "true", "java.package": "com.weibo.test", "php.with_package_dir": "false"}
+	code, _, err := GeneratByFileContent(files, &Config{CodeTemplates: "java,php,go", Options: options})
+	assert.Nil(err)
+	assert.NotNil(code[filepath.Join("com", "weibo", "test", "A.java")])
+	assert.NotNil(code["A.php"])
+	assert.NotNil(code[filepath.Join("test", "a", "a.go")])
+
+	// java option does not leak into other templates
+	assert.NotContains(code["A.php"], "com.weibo.test")
+	assert.NotContains(code["A.php"], "com\\weibo\\test")
+	assert.NotContains(code[filepath.Join("test", "a", "a.go")], "com.weibo.test")
+	assert.NotContains(code[filepath.Join("test", "a", "a.go")], "com/weibo/test")
+
+	// output directory of template
+	dir := t.TempDir()
+	config := &Config{CodeTemplates: "java,php", WritePath: dir, Options: options, TemplatePaths: map[string]string{"java": filepath.Join(dir, "src", "main", "java")}}
+	err = Generate("a.breeze", []byte(files["a.breeze"]), config)
+	assert.Nil(err)
+	_, err = os.Stat(filepath.Join(dir, "src", "main", "java", "com", "weibo", "test", "A.java"))
+	assert.Nil(err)
+	_, err = os.Stat(filepath.Join(dir, "php", "A.php"))
+	assert.Nil(err)
+
+	// files in old output directory are removed when output directory changes
+	config.TemplatePaths["java"] = filepath.Join(dir, "java-sdk")
+	err = Generate("a.breeze", []byte(files["a.breeze"]), config)
+	assert.Nil(err)
+	_, err = os.Stat(filepath.Join(dir, "java-sdk", "com", "weibo", "test", "A.java"))
+	assert.Nil(err)
+	_, err = os.Stat(filepath.Join(dir, "src", "main", "java", "com"))
+	assert.True(os.IsNotExist(err))
+}
@@ -0,0 +1,119 @@
+package outputs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+//DirOutput writes generated files into a directory, the files of each template are placed in a sub directory named by the template,
+//or in the directory of the template in Roots.
+//a manifest file is kept in the directory, so files of removed schemas can be deleted,
+//and files not generated by breeze-generator will not be overwritten unless Force is true.
+//existing files not in the manifest but with the same content as generated, such as files generated by an old version, are adopted into the manifest.
+type DirOutput struct {
+	Path  string
+	Force bool
+	// Roots are the output directories of templates, relative paths are relative to the current directory.
+	Roots map[string]string
+	// Keep reports whether the orphan files generated before by template from schema should be kept.
+	// all orphan files are deleted if Keep is nil.
+	Keep        func(template string, schema string) bool
+	retained    map[string]bool
+	oldManifest *manifest
+	newManifest *manifest
+}
+
+//NewDirOutput create a DirOutput with the manifest in path
+func NewDirOutput(path string, force bool) (*DirOutput, error) {
+	oldManifest, err := loadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	return &DirOutput{Path: path, Force: force, retained: make(map[string]bool), oldManifest: oldManifest, newManifest: newManifest()}, nil
+}
+
+//Retain keeps files generated before by template from schema, it's used when generating fail
+func (d *DirOutput) Retain(template string, schema string) {
+	d.retained[template+":"+schema] = true
+}
+
+//Write writes a generated file into the directory of its template
+func (d *DirOutput) Write(file *core.GeneratedFile) error {
+	root, err := d.root(file.Template)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(root, file.Name)
+	hash := contentHash(file.Content)
+	old := d.oldManifest.get(file.Template, file.Name)
+	if !d.Force && old == nil {
+		if content, err := ioutil.ReadFile(path); err == nil {
+			if contentHash(content) != hash {
+				return errors.New("refuse to overwrite file not generated by breeze-generator: " + path)
+			}
+			d.newManifest.put(file.Template, file.Name, &manifestFile{Schema: file.Schema, Hash: hash})
+			return nil
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if old != nil && old.Hash == hash { // not rewrite unchanged files, so their modification time keeps
+		if content, err := ioutil.ReadFile(path); err == nil && contentHash(content) == hash {
+			d.newManifest.put(file.Template, file.Name, &manifestFile{Schema: file.Schema, Hash: hash})
+			return nil
+		}
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(path, file.Content, 0666)
+	if err != nil {
+		return err
+	}
+	d.newManifest.put(file.Template, file.Name, &manifestFile{Schema: file.Schema, Hash: hash})
+	return nil
+}
+
+//Dir returns the output directory of template
+func (d *DirOutput) Dir(template string) string {
+	if root := d.Roots[template]; root != "" {
+		return root
+	}
+	return filepath.Join(d.Path, template)
+}
+
+// root returns the output directory of template, and records it in the manifest
+func (d *DirOutput) root(template string) (string, error) {
+	root := d.Roots[template]
+	if root == "" {
+		return filepath.Join(d.Path, template), nil
+	}
+	// paths in manifest are relative to the manifest directory if possible
+	if rel, err := relPath(d.Path, root); err == nil {
+		root = rel
+	} else if root, err = filepath.Abs(root); err != nil {
+		return "", err
+	}
+	d.newManifest.Roots[template] = filepath.ToSlash(root)
+	return d.newManifest.root(d.Path, template), nil
+}
+
+//Close deletes orphan files and saves the manifest
+func (d *DirOutput) Close() error {
+	d.newManifest.prune(d.oldManifest, d.Path, func(template string, file *manifestFile) bool {
+		if d.retained[template+":"+file.Schema] {
+			return true
+		}
+		return d.Keep != nil && d.Keep(template, file.Schema)
+	})
+	err := os.MkdirAll(d.Path, 0777)
+	if err != nil {
+		return err
+	}
+	return d.newManifest.save(d.Path)
+}
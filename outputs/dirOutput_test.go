@@ -0,0 +1,72 @@
+package outputs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	generator "github.com/weibreeze/breeze-generator"
+	"github.com/weibreeze/breeze-generator/outputs"
+)
+
+func TestManifest(t *testing.T) {
+	assert := assert2.New(t)
+	src := t.TempDir()
+	dest := t.TempDir()
+	if err := ioutil.WriteFile(src+"/a.breeze", []byte("package test.a;\nmessage A {\n    int32 id = 1;\n}\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(src+"/b.breeze", []byte("package test.b;\nmessage B {\n    string name = 1;\n}\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	config := &generator.Config{WritePath: dest, CodeTemplates: "java,php"}
+	_, err := generator.GeneratePath(src, config)
+	assert.Nil(err)
+	assert.FileExists(dest + "/" + outputs.ManifestFileName)
+	assert.FileExists(dest + "/java/B.java")
+	assert.FileExists(dest + "/php/B.php")
+
+	// orphan files of removed schema will be deleted
+	if err := os.Remove(src + "/b.breeze"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = generator.GeneratePath(src, config)
+	assert.Nil(err)
+	assert.FileExists(dest + "/java/A.java")
+	assert.NoFileExists(dest + "/java/B.java")
+	assert.NoFileExists(dest + "/php/B.php")
+	manifest, err := ioutil.ReadFile(dest + "/" + outputs.ManifestFileName)
+	assert.Nil(err)
+	assert.Contains(string(manifest), "A.java")
+	assert.NotContains(string(manifest), "B.java")
+
+	// files with the same content as generated are adopted when there is no manifest, such as generated by an old version
+	if err := os.Remove(dest + "/" + outputs.ManifestFileName); err != nil {
+		t.Fatal(err)
+	}
+	_, err = generator.GeneratePath(src, config)
+	assert.Nil(err)
+	manifest, err = ioutil.ReadFile(dest + "/" + outputs.ManifestFileName)
+	assert.Nil(err)
+	assert.Contains(string(manifest), "A.java")
+
+	// files not generated by breeze-generator will not be overwritten unless force
+	if err := os.Remove(dest + "/" + outputs.ManifestFileName); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(dest+"/java/A.java", []byte("custom"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	_, err = generator.GeneratePath(src, config)
+	assert.NotNil(err)
+	content, err := ioutil.ReadFile(dest + "/java/A.java")
+	assert.Nil(err)
+	assert.Equal("custom", string(content))
+	config.Force = true
+	_, err = generator.GeneratePath(src, config)
+	assert.Nil(err)
+	content, err = ioutil.ReadFile(dest + "/java/A.java")
+	assert.Nil(err)
+	assert.NotEqual("custom", string(content))
+}
@@ -0,0 +1,148 @@
+package outputs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ManifestFileName is the name of the manifest file written under WritePath
+const ManifestFileName = ".breeze-gen.json"
+
+const manifestVersion = 1
+
+// manifest records every file produced by the generator in a write path, grouped by template name.
+// it is used to delete orphan files of removed schemas and to protect files not generated by breeze-generator.
+type manifest struct {
+	Version   int                                 `json:"version"`
+	Roots     map[string]string                   `json:"roots,omitempty"` // output directories of templates not in write path, relative to write path
+	Templates map[string]map[string]*manifestFile `json:"templates"`
+}
+
+type manifestFile struct {
+	Schema string `json:"schema"`
+	Hash   string `json:"hash"`
+}
+
+func newManifest() *manifest {
+	return &manifest{Version: manifestVersion, Roots: make(map[string]string), Templates: make(map[string]map[string]*manifestFile)}
+}
+
+// loadManifest reads the manifest in write path. an empty manifest is returned if the manifest file not exists.
+func loadManifest(writePath string) (*manifest, error) {
+	content, err := ioutil.ReadFile(filepath.Join(writePath, ManifestFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newManifest(), nil
+		}
+		return nil, err
+	}
+	m := newManifest()
+	if err = json.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("wrong manifest file: %s, err:%s", ManifestFileName, err.Error())
+	}
+	if m.Templates == nil {
+		m.Templates = make(map[string]map[string]*manifestFile)
+	}
+	if m.Roots == nil {
+		m.Roots = make(map[string]string)
+	}
+	return m, nil
+}
+
+func (m *manifest) save(writePath string) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(writePath, ManifestFileName), append(content, '\n'), 0666)
+}
+
+func (m *manifest) get(template string, name string) *manifestFile {
+	if files := m.Templates[template]; files != nil {
+		return files[filepath.ToSlash(name)]
+	}
+	return nil
+}
+
+func (m *manifest) put(template string, name string, file *manifestFile) {
+	files := m.Templates[template]
+	if files == nil {
+		files = make(map[string]*manifestFile)
+		m.Templates[template] = files
+	}
+	files[filepath.ToSlash(name)] = file
+}
+
+// prune deletes files recorded in old manifest but not generated in this round, and files in the old output directory of templates.
+// files for which keep returns true will be kept in the new manifest if the output directory of their template not changes.
+func (m *manifest) prune(old *manifest, writePath string, keep func(template string, file *manifestFile) bool) {
+	for template, files := range old.Templates {
+		moved := m.Templates[template] != nil && old.root(writePath, template) != m.root(writePath, template)
+		for name, file := range files {
+			if m.get(template, name) != nil && !moved {
+				continue
+			}
+			if !moved && keep(template, file) {
+				m.put(template, name, file)
+				if _, ok := m.Roots[template]; !ok && old.Roots[template] != "" {
+					m.Roots[template] = old.Roots[template]
+				}
+				continue
+			}
+			root := old.root(writePath, template)
+			path := filepath.Join(root, filepath.FromSlash(name))
+			err := os.Remove(path)
+			if err != nil && !os.IsNotExist(err) {
+				fmt.Printf("warning: remove orphan file fail, file name:%s, err:%s\n", path, err.Error())
+				continue
+			}
+			removeEmptyDirs(filepath.Dir(path), root)
+		}
+	}
+}
+
+// root returns the output directory of template
+func (m *manifest) root(writePath string, template string) string {
+	root := m.Roots[template]
+	if root == "" {
+		return filepath.Join(writePath, template)
+	}
+	root = filepath.FromSlash(root)
+	if filepath.IsAbs(root) {
+		return root
+	}
+	return filepath.Join(writePath, root)
+}
+
+// relPath returns path relative to base, both of them are relative to the current directory
+func relPath(base string, path string) (string, error) {
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(absBase, absPath)
+}
+
+// removeEmptyDirs removes dir and its parents while they are empty, stop at root.
+func removeEmptyDirs(dir string, root string) {
+	for dir != root && len(dir) > len(root) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
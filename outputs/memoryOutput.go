@@ -0,0 +1,36 @@
+package outputs
+
+import (
+	"sync"
+
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+//MemoryOutput keeps generated files in memory, grouped by template name
+type MemoryOutput struct {
+	Files map[string]map[string][]byte
+	lock  sync.Mutex
+}
+
+//NewMemoryOutput create an empty MemoryOutput
+func NewMemoryOutput() *MemoryOutput {
+	return &MemoryOutput{Files: make(map[string]map[string][]byte)}
+}
+
+//Write keeps a generated file in memory
+func (m *MemoryOutput) Write(file *core.GeneratedFile) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	files := m.Files[file.Template]
+	if files == nil {
+		files = make(map[string][]byte)
+		m.Files[file.Template] = files
+	}
+	files[file.Name] = file.Content
+	return nil
+}
+
+//Close does nothing for MemoryOutput
+func (m *MemoryOutput) Close() error {
+	return nil
+}
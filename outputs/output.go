@@ -0,0 +1,11 @@
+package outputs
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// archivePath returns the slash separated path of a generated file in an archive.
+func archivePath(template string, name string) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Join(template, name)), "/")
+}
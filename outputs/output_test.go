@@ -0,0 +1,52 @@
+package outputs_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	generator "github.com/weibreeze/breeze-generator"
+	"github.com/weibreeze/breeze-generator/outputs"
+)
+
+func TestArchiveOutput(t *testing.T) {
+	assert := assert2.New(t)
+	content, err := ioutil.ReadFile(filepath.Join("..", "main", "demo.breeze"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := map[string]string{"with_motan_config": "true"}
+
+	zipBuf := &bytes.Buffer{}
+	err = generator.Generate("demo.breeze", content, &generator.Config{CodeTemplates: "java,go", Options: options, Output: outputs.NewZipOutput(zipBuf)})
+	assert.Nil(err)
+	reader, err := zip.NewReader(bytes.NewReader(zipBuf.Bytes()), int64(zipBuf.Len()))
+	assert.Nil(err)
+	zipNames := make([]string, 0, 16)
+	for _, f := range reader.File {
+		zipNames = append(zipNames, f.Name)
+	}
+	assert.Contains(zipNames, "java/com/weibo/motan/breeze/demo/User.java")
+	assert.Contains(zipNames, "go/demo/demo.go")
+	assert.Contains(zipNames, "motanConfig/demo-rpc.xml")
+
+	tarBuf := &bytes.Buffer{}
+	err = generator.Generate("demo.breeze", content, &generator.Config{CodeTemplates: "java,go", Options: options, Output: outputs.NewTarOutput(tarBuf)})
+	assert.Nil(err)
+	tarReader := tar.NewReader(tarBuf)
+	tarNames := make([]string, 0, 16)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(err)
+		tarNames = append(tarNames, header.Name)
+	}
+	assert.Equal(zipNames, tarNames)
+}
@@ -0,0 +1,35 @@
+package outputs
+
+import (
+	"archive/tar"
+	"io"
+	"time"
+
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+//TarOutput writes generated files into a tar stream, the files of each template are placed in a directory named by the template.
+type TarOutput struct {
+	writer *tar.Writer
+}
+
+//NewTarOutput create a TarOutput writing to w. w will not be closed by TarOutput
+func NewTarOutput(w io.Writer) *TarOutput {
+	return &TarOutput{writer: tar.NewWriter(w)}
+}
+
+//Write adds a generated file into the tar stream
+func (t *TarOutput) Write(file *core.GeneratedFile) error {
+	header := &tar.Header{Name: archivePath(file.Template, file.Name), Mode: 0666, Size: int64(len(file.Content)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+	err := t.writer.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = t.writer.Write(file.Content)
+	return err
+}
+
+//Close finishes the tar stream
+func (t *TarOutput) Close() error {
+	return t.writer.Close()
+}
@@ -0,0 +1,36 @@
+package outputs
+
+import (
+	"archive/zip"
+	"io"
+	"time"
+
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+//ZipOutput writes generated files into a zip archive, the files of each template are placed in a directory named by the template.
+type ZipOutput struct {
+	writer *zip.Writer
+}
+
+//NewZipOutput create a ZipOutput writing to w. w will not be closed by ZipOutput
+func NewZipOutput(w io.Writer) *ZipOutput {
+	return &ZipOutput{writer: zip.NewWriter(w)}
+}
+
+//Write adds a generated file into the zip archive
+func (z *ZipOutput) Write(file *core.GeneratedFile) error {
+	header := &zip.FileHeader{Name: archivePath(file.Template, file.Name), Method: zip.Deflate, Modified: time.Now()}
+	header.SetMode(0666)
+	w, err := z.writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(file.Content)
+	return err
+}
+
+//Close finishes the zip archive
+func (z *ZipOutput) Close() error {
+	return z.writer.Close()
+}
@@ -0,0 +1,39 @@
+package generator
+
+import (
+	"runtime"
+	"sync"
+)
+
+// runParallel calls job with index in [0, n) using at most concurrency goroutines, and waits until all jobs done.
+// if concurrency is not positive, runtime.NumCPU() is used.
+func runParallel(concurrency int, n int, job func(index int)) {
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	if concurrency > n {
+		concurrency = n
+	}
+	if concurrency <= 1 {
+		for i := 0; i < n; i++ {
+			job(i)
+		}
+		return
+	}
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				job(index)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,31 @@
+package generator
+
+import (
+	"io/ioutil"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func TestConcurrentGenerate(t *testing.T) {
+	assert := assert2.New(t)
+	files := make(map[string]string)
+	infos, err := ioutil.ReadDir("main/tests")
+	assert.Nil(err)
+	for _, info := range infos {
+		content, err := ioutil.ReadFile("main/tests/" + info.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[info.Name()] = string(content)
+	}
+	expectCode, expectConfig, err := GeneratByFileContent(files, &Config{CodeTemplates: "all", Concurrency: 1})
+	assert.Nil(err)
+	assert.NotEmpty(expectCode)
+	for i := 0; i < 5; i++ {
+		code, config, err := GeneratByFileContent(files, &Config{CodeTemplates: "all", Concurrency: 8})
+		assert.Nil(err)
+		assert.Equal(expectCode, code)
+		assert.Equal(expectConfig, config)
+	}
+}
@@ -25,7 +25,7 @@ const (
 var (
 	regPackage = regexp.MustCompile("^[\\w.]+$")
 	regName    = regexp.MustCompile("^[\\w]+$")
-	regField   = regexp.MustCompile("^([\\w<>., ]+) +(\\w+) *= *(\\d+) *$")
+	regField   = regexp.MustCompile("^([\\w<>., ]+) +(\\w+) *= *(\\d+) *(?:\\((.*)\\))? *$")
 )
 
 //BreezeParser can parse a schema according to breeze specification
@@ -56,7 +56,7 @@ func (b *BreezeParser) ParseSchema(content []byte, context *core.Context) (schem
 			processConfig(schema)
 			return schema, nil
 		}
-		err = process(line, buf, schema)
+		err = process(line, buf, schema, context)
 		if err != nil {
 			if err == io.EOF {
 				return nil, errors.New("unexpect end, maybe segment format not correct. segment line:" + line)
@@ -91,7 +91,7 @@ func appendOptions(options map[string]string, configs map[string]*core.Config) {
 	}
 }
 
-func process(line string, buf *bytes.Buffer, schema *core.Schema) error {
+func process(line string, buf *bytes.Buffer, schema *core.Schema, context *core.Context) error {
 	if line == "" {
 		return nil
 	}
@@ -109,17 +109,17 @@ func process(line string, buf *bytes.Buffer, schema *core.Schema) error {
 		if !regPackage.MatchString(schema.OrgPackage) {
 			return errors.New("package _name illegal. package: " + schema.OrgPackage)
 		}
-		if UniformPackage != "" { // file package
-			schema.Package = UniformPackage
+		if context.UniformPackage != "" { // file package
+			schema.Package = context.UniformPackage
 		}
 	case Message:
-		msg, err := parseMessage(buf, line)
+		msg, err := parseMessage(buf, line, context.UniformPackage != "")
 		if err != nil {
 			return err
 		}
 		schema.Messages[msg.Name] = msg
 	case Service:
-		service, err := parseService(buf, line)
+		service, err := parseService(buf, line, context.UniformPackage != "")
 		if err != nil {
 			return err
 		}
@@ -168,10 +168,10 @@ func parseOption(line string) (key string, value string, err error) {
 	return strings.TrimSpace(str[0]), strings.TrimSpace(str[1]), nil
 }
 
-func parseMessage(buf *bytes.Buffer, firstLine string) (message *core.Message, err error) {
+func parseMessage(buf *bytes.Buffer, firstLine string, removePackage bool) (message *core.Message, err error) {
 	message = &core.Message{Fields: make(map[int]*core.Field), Options: make(map[string]string)}
 	name, options, err := parseSegment(buf, firstLine, len(Message), func(line string) error {
-		field, innerErr := parseField(line)
+		field, innerErr := parseField(line, removePackage)
 		if innerErr != nil {
 			return innerErr
 		}
@@ -222,13 +222,13 @@ func parseEnum(buf *bytes.Buffer, firstLine string) (message *core.Message, err
 	return message, nil
 }
 
-func parseField(line string) (field *core.Field, err error) {
+func parseField(line string, removePackage bool) (field *core.Field, err error) {
 	result := regField.FindStringSubmatchIndex(line)
 	if len(result) < 8 {
 		return nil, errors.New("wrong field format. line:" + line)
 	}
 	tag := line[result[2]:result[3]]
-	tp, err := core.GetType(tag, UniformPackage != "")
+	tp, err := core.GetType(tag, removePackage)
 	if err != nil {
 		return nil, err
 	}
@@ -237,7 +237,26 @@ func parseField(line string) (field *core.Field, err error) {
 	if err != nil {
 		return nil, err
 	}
-	return &core.Field{Name: name, Type: tp, Index: index}, nil
+	field = &core.Field{Name: name, Type: tp, Index: index, Options: make(map[string]string)}
+	if result[8] > -1 { //has options
+		if err = parseOptions(line[result[8]:result[9]], line, field.Options); err != nil {
+			return nil, err
+		}
+	}
+	return field, nil
+}
+
+// parseOptions parses options like `a = b, c = d` into options
+func parseOptions(str string, line string, options map[string]string) error {
+	items := strings.Split(str, ",")
+	for _, item := range items {
+		op := strings.Split(item, "=")
+		if len(op) != 2 {
+			return errors.New("wrong options format. line:" + line)
+		}
+		options[strings.TrimSpace(op[0])] = strings.TrimSpace(op[1])
+	}
+	return nil
 }
 
 func parseSegment(buf *bytes.Buffer, firstLine string, start int, parseLine func(string) error) (name string, options map[string]string, err error) {
@@ -250,13 +269,8 @@ func parseSegment(buf *bytes.Buffer, firstLine string, start int, parseLine func
 		if end < 0 {
 			return "", nil, errors.New("wrong format. line:" + firstLine)
 		}
-		items := strings.Split(firstLine[index+1:end], ",")
-		for _, item := range items {
-			op := strings.Split(item, "=")
-			if len(op) != 2 {
-				return "", nil, errors.New("wrong options format. line:" + firstLine)
-			}
-			options[strings.TrimSpace(op[0])] = strings.TrimSpace(op[1])
+		if err = parseOptions(firstLine[index+1:end], firstLine, options); err != nil {
+			return "", nil, err
 		}
 	} else if index = strings.Index(firstLine, "{"); index > -1 { //end with '{'
 		name = firstLine[start:index]
@@ -295,10 +309,10 @@ func parseSegment(buf *bytes.Buffer, firstLine string, start int, parseLine func
 	}
 }
 
-func parseService(buf *bytes.Buffer, firstLine string) (service *core.Service, err error) {
+func parseService(buf *bytes.Buffer, firstLine string, removePackage bool) (service *core.Service, err error) {
 	service = &core.Service{Methods: make(map[string]*core.Method), Options: make(map[string]string)}
 	name, options, err := parseSegment(buf, firstLine, len(Service), func(line string) error {
-		method, innerErr := parseMethod(line)
+		method, innerErr := parseMethod(line, removePackage)
 		if innerErr != nil {
 			return innerErr
 		}
@@ -318,7 +332,7 @@ func parseService(buf *bytes.Buffer, firstLine string) (service *core.Service, e
 	return service, nil
 }
 
-func parseMethod(line string) (method *core.Method, err error) {
+func parseMethod(line string, removePackage bool) (method *core.Method, err error) {
 	index1 := strings.Index(line, "(")
 	index2 := strings.Index(line, ")")
 	if index1 < 0 || index2 < 0 || index2 < index1 {
@@ -340,7 +354,7 @@ func parseMethod(line string) (method *core.Method, err error) {
 				return nil, err
 			}
 			var tp *core.Type
-			tp, err = core.GetType(strings.TrimSpace(paramStr[:index]), UniformPackage != "")
+			tp, err = core.GetType(strings.TrimSpace(paramStr[:index]), removePackage)
 			if err != nil {
 				return nil, err
 			}
@@ -358,7 +372,7 @@ func parseMethod(line string) (method *core.Method, err error) {
 	var ret *core.Type
 	retStr := strings.TrimSpace(line[index2+1:])
 	if retStr != "" {
-		ret, err = core.GetType(retStr, UniformPackage != "")
+		ret, err = core.GetType(retStr, removePackage)
 		if err != nil {
 			return nil, err
 		}
@@ -409,7 +423,7 @@ func getTypeStr(str string) (index int, err error) {
 		if err != nil {
 			return 0, err
 		}
-		result += index
+		result += index + 1
 		result += strings.Index(str[result:], ">") + 1
 	} else if strings.HasPrefix(str[result:], "array<") {
 		result += 6
@@ -419,8 +433,12 @@ func getTypeStr(str string) (index int, err error) {
 		}
 		result += index
 		result += strings.Index(str[result:], ">") + 1
-	} else {
-		result += strings.Index(str[result:], " ")
+	} else { // simple type ends with the param name, or the end of map or array type
+		index = strings.IndexAny(str[result:], " ,>")
+		if index < 0 {
+			return 0, errors.New("wrong param format:" + str)
+		}
+		result += index
 	}
 	return result, nil
 }
@@ -16,10 +16,6 @@ const (
 	BreezeFileSuffix = ".breeze"
 )
 
-var (
-	UniformPackage = ""
-)
-
 var (
 	instances = map[string]core.Parser{
 		Breeze: &BreezeParser{},
@@ -0,0 +1,259 @@
+// Package plugin runs external code generators, so code for new targets can be generated without changing breeze-generator.
+//
+// a plugin named `name` is an executable `breeze-gen-<name>` in PATH. it reads a json Request from stdin,
+// which contains the descriptors of all schemas and the options, and writes a json Response to stdout,
+// which contains the generated files of each schema and diagnostics. a plugin written in go can use Run.
+package plugin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/weibreeze/breeze-generator/core"
+	"github.com/weibreeze/breeze-generator/descriptor"
+)
+
+//ProtocolVersion is the version of plugin protocol
+const ProtocolVersion = 1
+
+//ExecutablePrefix is the prefix of plugin executable names
+const ExecutablePrefix = "breeze-gen-"
+
+//diagnostic levels
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelInfo    = "info"
+)
+
+//Request is written to the stdin of plugin as json
+type Request struct {
+	Version   int                   `json:"version"`  // protocol version
+	Plugin    string                `json:"plugin"`   // plugin name
+	Generate  []string              `json:"generate"` // names of schemas to generate, other schemas are only referenced
+	Options   map[string]string     `json:"options,omitempty"`
+	SchemaSet *descriptor.SchemaSet `json:"schema_set"`
+}
+
+//Response is read from the stdout of plugin as json
+type Response struct {
+	Files       []*File       `json:"files,omitempty"`
+	Diagnostics []*Diagnostic `json:"diagnostics,omitempty"`
+}
+
+//File is a generated file
+type File struct {
+	Schema  string `json:"schema"` // name of the schema which the file is generated from
+	Name    string `json:"name"`   // slash separated path relative to the output directory of the plugin
+	Content string `json:"content"`
+}
+
+//Diagnostic is a message reported by plugin. the schema fails to generate if a diagnostic of error level is reported
+type Diagnostic struct {
+	Level   string `json:"level"`            // error, warning or info
+	Schema  string `json:"schema,omitempty"` // empty if the diagnostic is not about a schema
+	Message string `json:"message"`
+}
+
+//Template is a core.CodeTemplate which generates code by a plugin.
+//the plugin runs once for all schemas in a context, and the generated files are returned by schema.
+//the result is kept for the schemas and options of the last run, the plugin runs again when they change.
+type Template struct {
+	name    string
+	Command string // executable of the plugin
+	lock    sync.Mutex
+	key     string // hash of schemas and options of the last run
+	files   map[string]map[string][]byte
+	errs    map[string]error
+	err     error
+}
+
+//New create a plugin Template. plugin can be a name, then `breeze-gen-<name>` is found in PATH,
+//or a path of the executable, then the name is the file name without prefix `breeze-gen-`.
+func New(plugin string) *Template {
+	if strings.ContainsAny(plugin, `/\`) {
+		name := strings.TrimSuffix(filepath.Base(plugin), filepath.Ext(plugin))
+		return &Template{name: strings.TrimPrefix(name, ExecutablePrefix), Command: plugin}
+	}
+	return &Template{name: plugin, Command: ExecutablePrefix + plugin}
+}
+
+//Name : template name, it is the plugin name
+func (t *Template) Name() string {
+	return t.name
+}
+
+//GenerateCode : generate code by plugin. the plugin runs when the first schema of a context is generated
+func (t *Template) GenerateCode(schema *core.Schema, context *core.Context) (contents map[string][]byte, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if key := contextKey(context); t.key != key {
+		t.key = key
+		t.run(context)
+	}
+	if t.err != nil {
+		return nil, t.err
+	}
+	if t.errs[schema.Name] != nil {
+		return nil, t.errs[schema.Name]
+	}
+	contents = t.files[schema.Name]
+	if contents == nil {
+		contents = make(map[string][]byte)
+	}
+	return contents, nil
+}
+
+//CacheKey : the path and content hash of the plugin executable, so the cached code is regenerated when the plugin changes
+func (t *Template) CacheKey(context *core.Context) string {
+	path, err := exec.LookPath(t.Command)
+	if err != nil {
+		return t.Command + ":" + err.Error()
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return path + ":" + err.Error()
+	}
+	hash := sha256.Sum256(content)
+	return path + ":" + hex.EncodeToString(hash[:])
+}
+
+func (t *Template) run(context *core.Context) {
+	t.files = make(map[string]map[string][]byte)
+	t.errs = make(map[string]error)
+	request := &Request{Version: ProtocolVersion, Plugin: t.name, Options: context.Options, SchemaSet: descriptor.FromContext(context)}
+	for _, schema := range request.SchemaSet.Schemas {
+		request.Generate = append(request.Generate, schema.Name)
+	}
+	var response *Response
+	response, t.err = t.call(request)
+	if t.err != nil {
+		return
+	}
+	for _, diagnostic := range response.Diagnostics {
+		message := "plugin " + t.name + ": " + diagnostic.Message
+		if diagnostic.Schema != "" {
+			message = "plugin " + t.name + ": " + diagnostic.Schema + ": " + diagnostic.Message
+		}
+		if diagnostic.Level != LevelError {
+			fmt.Printf("%s: %s\n", diagnostic.Level, message)
+			continue
+		}
+		if diagnostic.Schema == "" {
+			t.err = errors.New(message)
+			return
+		}
+		if t.errs[diagnostic.Schema] == nil {
+			t.errs[diagnostic.Schema] = errors.New(message)
+		}
+	}
+	for _, file := range response.Files {
+		if context.GetSchema(file.Schema) == nil {
+			fmt.Printf("warning: plugin %s: ignore file %s of unknown schema %s\n", t.name, file.Name, file.Schema)
+			continue
+		}
+		name, ok := localFileName(file.Name)
+		if !ok {
+			t.errs[file.Schema] = fmt.Errorf("plugin %s: wrong file name: %s", t.name, file.Name)
+			continue
+		}
+		if t.files[file.Schema] == nil {
+			t.files[file.Schema] = make(map[string][]byte)
+		}
+		t.files[file.Schema][filepath.FromSlash(name)] = []byte(file.Content)
+	}
+}
+
+// contextKey returns the hash of content and options of schemas and options of context
+func contextKey(context *core.Context) string {
+	hash := sha256.New()
+	writeOptions := func(options map[string]string) {
+		keys := make([]string, 0, len(options))
+		for k := range options {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(hash, "%q=%q\n", k, options[k])
+		}
+		hash.Write([]byte{'\n'})
+	}
+	writeOptions(context.Options)
+	for _, schema := range context.SortedSchemas() {
+		fmt.Fprintf(hash, "%q:%s\n", schema.Name, schema.Hash)
+		writeOptions(schema.Options)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// localFileName returns the slash separated clean name if name is a relative path in the output directory.
+// both `/` and `\` are separators, so names such as `..\a` are rejected on all platforms,
+// and absolute paths and names with `:`, such as `C:a` with a volume name, are rejected too.
+func localFileName(name string) (string, bool) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if name == "" || strings.HasPrefix(name, "/") || strings.ContainsAny(name, ":\x00") {
+		return "", false
+	}
+	name = path.Clean(name)
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
+
+func (t *Template) call(request *Request) (*Response, error) {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	stdout := &bytes.Buffer{}
+	cmd := exec.Command(t.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("run plugin %s fail: %s", t.name, err.Error())
+	}
+	response := &Response{}
+	if err = json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return nil, fmt.Errorf("wrong response of plugin %s: %s", t.name, err.Error())
+	}
+	return response, nil
+}
+
+//Run is a helper for plugins written in go. it reads the request from stdin, calls generate,
+//and writes the response to stdout. the process exits with status 1 if generate returns an error.
+func Run(generate func(request *Request) (*Response, error)) {
+	if err := run(os.Stdin, os.Stdout, generate); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(r io.Reader, w io.Writer, generate func(request *Request) (*Response, error)) error {
+	request := &Request{}
+	if err := json.NewDecoder(r).Decode(request); err != nil {
+		return err
+	}
+	if request.Version != ProtocolVersion {
+		return fmt.Errorf("unsupported protocol version: %d", request.Version)
+	}
+	response, err := generate(request)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,190 @@
+package plugin_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	generator "github.com/weibreeze/breeze-generator"
+	"github.com/weibreeze/breeze-generator/core"
+	"github.com/weibreeze/breeze-generator/plugin"
+)
+
+// the test binary works as a plugin when it is run by generator
+func TestMain(m *testing.M) {
+	if os.Getenv("BREEZE_TEST_PLUGIN") == "1" {
+		plugin.Run(echo)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// echo generates a file describing the fields of each message
+func echo(request *plugin.Request) (*plugin.Response, error) {
+	response := &plugin.Response{}
+	generate := make(map[string]bool)
+	for _, name := range request.Generate {
+		generate[name] = true
+	}
+	for _, schema := range request.SchemaSet.Schemas {
+		if !generate[schema.Name] {
+			continue
+		}
+		if strings.HasPrefix(schema.Name, "bad") {
+			response.Diagnostics = append(response.Diagnostics, &plugin.Diagnostic{Level: plugin.LevelError, Schema: schema.Name, Message: "bad schema"})
+			continue
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "prefix:%s\n", request.Options["prefix"])
+		for _, message := range schema.Messages {
+			for _, field := range message.Fields {
+				fmt.Fprintf(&sb, "%s.%s:%s %s\n", message.FullName, field.Name, field.Type.Kind, field.Type.Name)
+			}
+		}
+		name := "echo/" + schema.Package + ".txt"
+		if request.Options["name"] != "" {
+			name = request.Options["name"]
+		}
+		response.Files = append(response.Files, &plugin.File{Schema: schema.Name, Name: name, Content: sb.String()})
+	}
+	response.Diagnostics = append(response.Diagnostics, &plugin.Diagnostic{Level: plugin.LevelWarning, Message: "generated by echo"})
+	return response, nil
+}
+
+func TestPlugin(t *testing.T) {
+	assert := assert2.New(t)
+	dir := t.TempDir()
+	executable, err := os.Executable()
+	assert.Nil(err)
+	assert.Nil(os.Symlink(executable, filepath.Join(dir, plugin.ExecutablePrefix+"echo")))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	os.Setenv("BREEZE_TEST_PLUGIN", "1")
+	defer os.Unsetenv("BREEZE_TEST_PLUGIN")
+
+	files := map[string]string{
+		"a.breeze": "package test.a;\nmessage A {\n    test.b.B b = 1;\n    test.b.E e = 2;\n    map<string, int32> m = 3;\n}\n",
+		"b.breeze": "package test.b;\nmessage B {\n    int32 id = 1;\n}\nenum E {\n    X = 1;\n}\n",
+	}
+	config := &generator.Config{Plugins: []string{"echo"}, Options: map[string]string{"echo.prefix": "p", "prefix": "common"}}
+	code, _, err := generator.GeneratByFileContent(files, config)
+	assert.Nil(err)
+	assert.Equal(2, len(code))
+	assert.Equal("prefix:p\ntest.a.A.b:message test.b.B\ntest.a.A.e:enum test.b.E\ntest.a.A.m:map \n", code[filepath.Join("echo", "test.a.txt")])
+
+	// plugins work with templates, and a schema reported error fails
+	files["bad.breeze"] = "package test.bad;\nmessage Bad {\n    int32 id = 1;\n}\n"
+	config = &generator.Config{CodeTemplates: "java", Plugins: []string{"echo"}}
+	_, _, err = generator.GeneratByFileContent(files, config)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "bad schema")
+
+	_, _, err = generator.GeneratByFileContent(files, &generator.Config{Plugins: []string{"not_exist"}})
+	assert.NotNil(err)
+	_, _, err = generator.GeneratByFileContent(files, &generator.Config{Plugins: []string{"java"}})
+	assert.NotNil(err)
+}
+
+func TestPluginCache(t *testing.T) {
+	assert := assert2.New(t)
+	dir := t.TempDir()
+	executable, err := os.Executable()
+	assert.Nil(err)
+	content, err := ioutil.ReadFile(executable)
+	assert.Nil(err)
+	command := filepath.Join(dir, plugin.ExecutablePrefix+"echo")
+	assert.Nil(ioutil.WriteFile(command, content, 0755))
+	os.Setenv("BREEZE_TEST_PLUGIN", "1")
+	defer os.Unsetenv("BREEZE_TEST_PLUGIN")
+
+	files := map[string]string{"a.breeze": "package test.a;\nmessage A {\n    int32 id = 1;\n}\n"}
+	cache, _ := generator.NewCache("")
+	for i := 0; i < 2; i++ {
+		_, _, err = generator.GeneratByFileContent(files, &generator.Config{Plugins: []string{command}, Cache: cache})
+		assert.Nil(err)
+	}
+	hits, misses := cache.Stats()
+	assert.Equal(1, hits)
+	assert.Equal(1, misses)
+
+	// the plugin is rebuilt, so the cached code is regenerated
+	assert.Nil(ioutil.WriteFile(command, append(content, 0), 0755))
+	_, _, err = generator.GeneratByFileContent(files, &generator.Config{Plugins: []string{command}, Cache: cache})
+	assert.Nil(err)
+	hits, misses = cache.Stats()
+	assert.Equal(1, hits)
+	assert.Equal(2, misses)
+}
+
+func TestPluginFileName(t *testing.T) {
+	assert := assert2.New(t)
+	dir := t.TempDir()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Symlink(executable, filepath.Join(dir, plugin.ExecutablePrefix+"echo")); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("BREEZE_TEST_PLUGIN", "1")
+	defer os.Unsetenv("BREEZE_TEST_PLUGIN")
+
+	files := map[string]string{"a.breeze": "package test.a;\nmessage A {\n    int32 id = 1;\n}\n"}
+	command := filepath.Join(dir, plugin.ExecutablePrefix+"echo")
+	for _, name := range []string{"", ".", "..", "../a.txt", `..\a.txt`, `a\..\..\a.txt`, "/a.txt", `\a.txt`, `C:\a.txt`, "C:a.txt", `\\host\share\a.txt`} {
+		_, _, err = generator.GeneratByFileContent(files, &generator.Config{Plugins: []string{command}, Options: map[string]string{"echo.name": name}})
+		if name == "" { // default name
+			assert.Nil(err)
+			continue
+		}
+		if assert.NotNil(err, name) {
+			assert.Contains(err.Error(), "wrong file name", name)
+		}
+	}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{Plugins: []string{command}, Options: map[string]string{"echo.name": `a\.\b.txt`}})
+	assert.Nil(err)
+	assert.Contains(code, filepath.Join("a", "b.txt"))
+}
+
+func TestPluginRunsAgain(t *testing.T) {
+	assert := assert2.New(t)
+	dir := t.TempDir()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Symlink(executable, filepath.Join(dir, plugin.ExecutablePrefix+"echo")); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("BREEZE_TEST_PLUGIN", "1")
+	defer os.Unsetenv("BREEZE_TEST_PLUGIN")
+
+	// the result of a run is not reused after a schema is added to the context
+	template := plugin.New(filepath.Join(dir, plugin.ExecutablePrefix+"echo"))
+	context := &core.Context{Options: map[string]string{}}
+	a := &core.Schema{Name: "a.breeze", Package: "test.a", Hash: "a"}
+	context.AddSchema(a)
+	code, err := template.GenerateCode(a, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(code, filepath.Join("echo", "test.a.txt"))
+	b := &core.Schema{Name: "b.breeze", Package: "test.b", Hash: "b"}
+	context.AddSchema(b)
+	code, err = template.GenerateCode(b, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(code, filepath.Join("echo", "test.b.txt"))
+
+	// nor after options are changed
+	context.Options["prefix"] = "changed"
+	code, err = template.GenerateCode(b, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(string(code[filepath.Join("echo", "test.b.txt")]), "prefix:changed")
+}
@@ -0,0 +1,264 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/weibreeze/breeze-generator/plugin"
+	"github.com/weibreeze/breeze-generator/templates"
+	"gopkg.in/yaml.v3"
+)
+
+//ProjectFileNames are the names of project file, FindProject searches them in order
+var ProjectFileNames = []string{"breeze.yaml", "breeze.yml", "breeze.json"}
+
+//Project is the project file of breeze-generator, written in yaml or json. for example:
+//
+//	sources: [schemas]
+//	excludes: ["**/testdata"]
+//	templates: [java, go]
+//	plugins: [doc]
+//	output: autoGenerate
+//	options:
+//	  with_package_dir: true
+//	languages:
+//	  java:
+//	    output: src/main/java
+//	    options:
+//	      java_package: com.weibo.breeze
+//	  go:
+//	    options:
+//	      go_package_prefix: github.com/weibreeze/demo/
+//
+//relative paths are relative to the directory of the project file.
+type Project struct {
+	Sources        []string                    `json:"sources" yaml:"sources"`                 // directories or files of schemas
+	Includes       []string                    `json:"includes" yaml:"includes"`               // glob patterns of schema files to include
+	Excludes       []string                    `json:"excludes" yaml:"excludes"`               // glob patterns of schema files or directories to exclude
+	Templates      []string                    `json:"templates" yaml:"templates"`             // names of code templates, all templates are used if empty and no plugins
+	Plugins        []string                    `json:"plugins" yaml:"plugins"`                 // names or paths of external generator plugins
+	TextTemplates  []string                    `json:"text_templates" yaml:"text_templates"`   // directories of text templates
+	Output         string                      `json:"output" yaml:"output"`                   // write path, languages without their own output are written into <output>/<language>
+	Options        ProjectOptions              `json:"options" yaml:"options"`                 // options for all languages
+	Languages      map[string]*LanguageProject `json:"languages" yaml:"languages"`             // output and options of each language, keyed by template name
+	Force          bool                        `json:"force" yaml:"force"`                     // same as Config.Force
+	Concurrency    int                         `json:"concurrency" yaml:"concurrency"`         // same as Config.Concurrency
+	UniformPackage string                      `json:"uniform_package" yaml:"uniform_package"` // same as Config.UniformPackage
+	Cache          string                      `json:"cache" yaml:"cache"`                     // cache file for incremental generation
+}
+
+//LanguageProject is the output directory and options of a language in Project
+type LanguageProject struct {
+	Output  string         `json:"output" yaml:"output"`   // generated files are written into this directory directly
+	Options ProjectOptions `json:"options" yaml:"options"` // override the options of Project
+}
+
+//ProjectOptions are options in project file. values can be any scalar, such as `with_package_dir: true`
+type ProjectOptions map[string]string
+
+//UnmarshalJSON converts scalar values into strings
+func (o *ProjectOptions) UnmarshalJSON(data []byte) error {
+	values := make(map[string]interface{})
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	return o.set(values)
+}
+
+//UnmarshalYAML converts scalar values into strings
+func (o *ProjectOptions) UnmarshalYAML(node *yaml.Node) error {
+	values := make(map[string]interface{})
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	return o.set(values)
+}
+
+func (o *ProjectOptions) set(values map[string]interface{}) error {
+	*o = make(ProjectOptions, len(values))
+	for k, v := range values {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return fmt.Errorf("option %s must be a scalar value", k)
+		case nil:
+			(*o)[k] = ""
+		default:
+			(*o)[k] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+//FindProject searches project file in dir and its parent directories, returns empty string if not found
+func FindProject(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range ProjectFileNames {
+			path := filepath.Join(dir, name)
+			if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+				return path, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+//LoadProject loads a project file. the file is parsed as json if its suffix is .json, otherwise as yaml.
+//relative paths in the file are converted to paths relative to the current directory.
+func LoadProject(path string) (*Project, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	project := &Project{}
+	if strings.HasSuffix(path, ".json") {
+		err = json.Unmarshal(content, project)
+	} else {
+		err = yaml.Unmarshal(content, project)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("wrong project file: %s, err:%s", path, err.Error())
+	}
+	dir := filepath.Dir(path)
+	for i, source := range project.Sources {
+		project.Sources[i] = joinPath(dir, source)
+	}
+	for i, t := range project.TextTemplates {
+		project.TextTemplates[i] = joinPath(dir, t)
+	}
+	project.Output = joinPath(dir, project.Output)
+	project.Cache = joinPath(dir, project.Cache)
+	for _, language := range project.Languages {
+		if language != nil {
+			language.Output = joinPath(dir, language.Output)
+		}
+	}
+	return project, nil
+}
+
+func joinPath(dir string, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+//SetOption sets an option for all languages, the option of each language is overridden
+func (p *Project) SetOption(key string, value string) {
+	if p.Options == nil {
+		p.Options = make(ProjectOptions)
+	}
+	p.Options[key] = value
+	for name, language := range p.Languages {
+		if language == nil {
+			continue
+		}
+		ts, err := templates.GetTemplate(name)
+		for k := range language.Options {
+			if k == key {
+				delete(language.Options, k)
+			} else if err == nil && len(ts) == 1 {
+				if resolved, ok := optionKey(ts[0], k); ok && resolved == key {
+					delete(language.Options, k)
+				}
+			}
+		}
+	}
+}
+
+//SetDefaultOption sets an option for all languages if the project does not set it, options of each language are kept
+func (p *Project) SetDefaultOption(key string, value string) {
+	if _, ok := p.Options[key]; ok {
+		return
+	}
+	if p.Options == nil {
+		p.Options = make(ProjectOptions)
+	}
+	p.Options[key] = value
+}
+
+//SetOutput sets the output directory of all languages
+func (p *Project) SetOutput(output string) {
+	p.Output = output
+	for _, language := range p.Languages {
+		if language != nil {
+			language.Output = ""
+		}
+	}
+}
+
+//Config builds the generate config of project. the output directory of a language is used as its template path,
+//and options of a language are converted to namespaced options, such as `java.java_package`.
+func (p *Project) Config() (*Config, error) {
+	if p.Output == "" {
+		return nil, errors.New("no output directory in project")
+	}
+	config := &Config{CodeTemplates: strings.Join(p.Templates, ","), WritePath: p.Output, Options: make(map[string]string, len(p.Options)),
+		Force: p.Force, Concurrency: p.Concurrency, UniformPackage: p.UniformPackage, Includes: p.Includes, Excludes: p.Excludes}
+	if len(p.Templates) == 0 && len(p.Plugins) == 0 && len(p.TextTemplates) == 0 {
+		config.CodeTemplates = templates.All
+	}
+	plugins := make(map[string]bool, len(p.Plugins)) // names of plugins and text templates
+	for _, name := range p.Plugins {
+		config.Plugins = append(config.Plugins, name)
+		plugins[plugin.New(name).Name()] = true
+	}
+	config.TextTemplates = p.TextTemplates
+	for _, dir := range p.TextTemplates {
+		if t, err := templates.LoadTextTemplate(dir); err == nil {
+			plugins[t.Name()] = true
+		}
+	}
+	for k, v := range p.Options {
+		config.Options[k] = v
+	}
+	for name, language := range p.Languages {
+		if _, err := templates.GetTemplate(name); err != nil && !plugins[name] {
+			fmt.Printf("warning: unknown language in project: %s\n", name)
+			continue
+		}
+		if language == nil {
+			continue
+		}
+		if language.Output != "" {
+			if config.TemplatePaths == nil {
+				config.TemplatePaths = make(map[string]string)
+			}
+			config.TemplatePaths[name] = language.Output
+		}
+		for k, v := range language.Options {
+			config.Options[name+"."+k] = v
+		}
+	}
+	if p.Cache != "" {
+		var err error
+		if config.Cache, err = NewCache(p.Cache); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+//GenerateProject generates code of all languages in project, returns the schema names
+func GenerateProject(project *Project) ([]string, error) {
+	if len(project.Sources) == 0 {
+		return nil, errors.New("no source in project")
+	}
+	config, err := project.Config()
+	if err != nil {
+		return nil, err
+	}
+	return GeneratePaths(project.Sources, config)
+}
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+func TestProject(t *testing.T) {
+	assert := assert2.New(t)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "schemas", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "schemas", "a.breeze"), []byte("package test.a;\nmessage A {\n    int32 id = 1;\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "schemas", "sub", "b.breeze"), []byte("package test.b;\nmessage B {\n    int32 id = 1;\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "breeze.yaml"), []byte(`
+sources: [schemas]
+excludes: [sub]
+templates: [java, go, php]
+output: gen
+options:
+  with_package_dir: true
+languages:
+  java:
+    output: sdk
+    options:
+      java_package: com.weibo.test
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "sub", "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path, err := FindProject(filepath.Join(dir, "sub", "dir"))
+	assert.Nil(err)
+	assert.Equal(filepath.Join(dir, "breeze.yaml"), path)
+
+	project, err := LoadProject(path)
+	assert.Nil(err)
+	assert.Equal([]string{filepath.Join(dir, "schemas")}, project.Sources)
+	assert.Equal("true", project.Options[core.WithPackageDir])
+	config, err := project.Config()
+	assert.Nil(err)
+	assert.Equal("java,go,php", config.CodeTemplates)
+	assert.Equal("com.weibo.test", config.Options["java.java_package"])
+	assert.Equal(filepath.Join(dir, "sdk"), config.TemplatePaths["java"])
+
+	names, err := GenerateProject(project)
+	assert.Nil(err)
+	assert.Equal([]string{"a.breeze"}, names)
+	_, err = os.Stat(filepath.Join(dir, "sdk", "com", "weibo", "test", "A.java"))
+	assert.Nil(err)
+	_, err = os.Stat(filepath.Join(dir, "gen", "go", "test", "a", "a.go"))
+	assert.Nil(err)
+
+	// options set by command line take precedence
+	project.SetOption(core.JavaPackage, "com.weibo.cmd")
+	project.SetOutput(filepath.Join(dir, "cmd"))
+	config, err = project.Config()
+	assert.Nil(err)
+	assert.Equal(0, len(config.TemplatePaths))
+	assert.Equal("com.weibo.cmd", config.Options[core.JavaPackage])
+	assert.Equal("", config.Options["java.java_package"])
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "breeze.json"), []byte(`{"sources": ["schemas"], "options": {"with_package_dir": false, "x": 1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, err = LoadProject(filepath.Join(dir, "breeze.json"))
+	assert.Nil(err)
+	assert.Equal("false", project.Options[core.WithPackageDir])
+	assert.Equal("1", project.Options["x"])
+
+	// default options do not override options of languages
+	if err := ioutil.WriteFile(filepath.Join(dir, "default.yaml"), []byte(`
+sources: [schemas]
+excludes: [sub]
+templates: [java, go]
+output: default
+languages:
+  go:
+    options:
+      with_package_dir: false
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, err = LoadProject(filepath.Join(dir, "default.yaml"))
+	assert.Nil(err)
+	project.SetDefaultOption(core.WithPackageDir, "true")
+	config, err = project.Config()
+	assert.Nil(err)
+	assert.Equal("true", config.Options[core.WithPackageDir])
+	assert.Equal("false", config.Options["go.with_package_dir"])
+	_, err = GenerateProject(project)
+	assert.Nil(err)
+	assert.FileExists(filepath.Join(dir, "default", "go", "a.go"))
+	assert.FileExists(filepath.Join(dir, "default", "java", "test", "a", "A.java"))
+}
@@ -8,8 +8,10 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/weibreeze/breeze-generator/core"
+	"github.com/weibreeze/breeze-generator/descriptor"
 )
 
 //CodeTemplate names
@@ -23,6 +25,11 @@ const (
 )
 
 var (
+	withPackageDirOption  = &core.Option{Name: core.WithPackageDir, Key: core.WithPackageDir, Description: "put generated files into directories of their packages"}
+	withDescriptorOption  = &core.Option{Name: core.WithDescriptor, Key: core.WithDescriptor, Description: "embed the binary descriptor set of schema into generated code"}
+	keepUnknownEnumOption = &core.Option{Name: core.KeepUnknownEnum, Key: core.KeepUnknownEnum, Description: "keep unknown enum numbers when decoding, so they can be encoded unchanged"}
+	withTestsOption       = &core.Option{Name: core.WithTests, Key: core.WithTests, Description: "generate tests which encode and decode messages with pseudo-random values, and go benchmarks"}
+
 	instances = map[string]core.CodeTemplate{
 		Php:  &PHPTemplate{},
 		Java: &JavaTemplate{},
@@ -39,6 +46,7 @@ func GetTemplate(names string) ([]core.CodeTemplate, error) {
 		for _, t := range instances {
 			templates = append(templates, t)
 		}
+		sort.Slice(templates, func(i, j int) bool { return templates[i].Name() < templates[j].Name() })
 		return templates, nil
 	}
 	arr := strings.Split(names, ",")
@@ -109,6 +117,32 @@ func sortMethods(service *core.Service) []*core.Method {
 	return methods
 }
 
+func sortServices(schema *core.Schema) []*core.Service {
+	names := make([]string, 0, len(schema.Services))
+	for name := range schema.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	services := make([]*core.Service, 0, len(names))
+	for _, name := range names {
+		services = append(services, schema.Services[name])
+	}
+	return services
+}
+
+func sortParams(method *core.Method) []*core.Param {
+	indexes := make([]int, 0, len(method.Params))
+	for index := range method.Params {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	params := make([]*core.Param, 0, len(indexes))
+	for _, index := range indexes {
+		params = append(params, method.Params[index])
+	}
+	return params
+}
+
 func sortUnique(a []string) []string {
 	m := make(map[string]bool, len(a))
 	for _, v := range a {
@@ -138,6 +172,36 @@ func firstLower(s string) string {
 	return strings.ToLower(s[:1]) + s[1:]
 }
 
+// snakeCase converts names like `userName` or `HTTPServer` to `user_name` and `http_server`
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && runes[i-1] != '_' && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// lowerCamelCase converts names like `user_name` or `UserName` to `userName`
+func lowerCamelCase(s string) string {
+	items := strings.Split(s, "_")
+	var result string
+	for _, item := range items {
+		if result == "" {
+			result = firstLower(item)
+		} else {
+			result += firstUpper(item)
+		}
+	}
+	return result
+}
+
 func writeGenerateComment(buf *bytes.Buffer, schemaName string) {
 	buf.WriteString("/*\n * Generated by breeze-generator (https://github.com/weibreeze/breeze-generator)\n * Schema: " + schemaName + "\n * Date: " + time.Now().Format("2006/1/2") + "\n */\n")
 }
@@ -169,10 +233,22 @@ func isEnum(tp *core.Type, schema *core.Schema, context *core.Context) bool {
 	if strings.Index(name, ".") < 0 {
 		name = schema.Package + "." + name
 	}
-	msg := context.Messages[name]
+	msg := context.GetMessage(name)
 	return msg != nil && msg.IsEnum
 }
 
+// check whether tp is an enum which keeps unknown numbers
+func isKeepUnknownEnum(tp *core.Type, schema *core.Schema, context *core.Context) bool {
+	if !isEnum(tp, schema, context) {
+		return false
+	}
+	name := tp.Name
+	if strings.Index(name, ".") < 0 {
+		name = schema.Package + "." + name
+	}
+	return keepUnknownEnum(context.GetMessage(name))
+}
+
 func toCamelCase(pkg string, seperator string) string {
 	items := strings.Split(pkg, ".")
 	if len(items) == 0 {
@@ -190,3 +266,60 @@ func toCamelCase(pkg string, seperator string) string {
 	}
 	return ns
 }
+
+// binaryDescriptor returns the binary descriptor set of schema, nil if option with_descriptor is not true
+func binaryDescriptor(schema *core.Schema, context *core.Context) ([]byte, error) {
+	if b, _ := strconv.ParseBool(schema.Options[core.WithDescriptor]); !b {
+		return nil, nil
+	}
+	set := &descriptor.SchemaSet{Version: descriptor.Version, Schemas: []*descriptor.Schema{descriptor.FromSchema(schema, context)}}
+	return descriptor.MarshalBinary(set)
+}
+
+// keepUnknownEnum returns whether unknown numbers of enum are kept, it can be set for an enum or globally
+func keepUnknownEnum(enum *core.Message) bool {
+	b, _ := strconv.ParseBool(enum.Options[core.KeepUnknownEnum])
+	return b
+}
+
+// descriptorName returns the name of embedded descriptor set of schema, such as `JavaUtilDateBreezeDescriptor` for java.util.Date.breeze
+func descriptorName(schema *core.Schema) string {
+	return schemaClassName(schema) + "BreezeDescriptor"
+}
+
+// schemaClassName returns the camel case name of schema file, such as `JavaUtilDate` for java.util.Date.breeze
+func schemaClassName(schema *core.Schema) string {
+	name := schema.Name
+	if index := strings.LastIndex(name, "."); index > 0 { // remove suffix of schema file
+		name = name[:index]
+	}
+	var ns string
+	for _, item := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		ns += firstUpper(item)
+	}
+	if ns == "" || unicode.IsDigit(rune(ns[0])) {
+		ns = "Schema" + ns
+	}
+	return ns
+}
+
+// withTests returns whether round-trip tests of messages are generated for schema
+func withTests(schema *core.Schema) bool {
+	b, _ := strconv.ParseBool(schema.Options[core.WithTests])
+	return b
+}
+
+// schemaMessage returns the message or enum of type tp if it is declared in schema, otherwise nil
+func schemaMessage(tp *core.Type, schema *core.Schema) *core.Message {
+	if tp.Number != core.Msg {
+		return nil
+	}
+	name := tp.Name
+	if index := strings.LastIndex(name, "."); index > -1 {
+		if name[:index] != schema.Package {
+			return nil
+		}
+		name = name[index+1:]
+	}
+	return schema.Messages[name]
+}
@@ -0,0 +1,159 @@
+package templates_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	generator "github.com/weibreeze/breeze-generator"
+)
+
+func TestKeepUnknownEnum(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{
+		"k.breeze": "package test.k;\nenum E(keep_unknown_enum = true) {\n    A = 1;\n    B = 2;\n}\nenum F {\n    X = 1;\n}\n" +
+			"message M {\n    E e = 1;\n    F f = 2;\n}\n",
+	}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{})
+	assert.Nil(err)
+	// java enums keep UNRECOGNIZED as a constant, raw numbers of enum fields are kept by messages
+	assert.Contains(code["E.java"], "public enum E {\n    A(1),\n    B(2),\n    UNRECOGNIZED(-1);\n")
+	assert.Contains(code["E.java"], "            lastUnrecognizedNumber.set(number[0]);\n            return UNRECOGNIZED;\n")
+	assert.Contains(code["E.java"], "        return value != null ? value : UNRECOGNIZED;\n")
+	assert.Contains(code["E.java"], "                throw new BreezeException(\"raw number of UNRECOGNIZED E is unknown, only enum fields of messages keep raw numbers\");\n")
+	assert.Contains(code["E.java"], "            if (number[0] == null) {\n                throw new BreezeException(\"missing number of enum E\");\n")
+	assert.Contains(code["F.java"], "            if (number[0] == null) {\n                throw new BreezeException(\"missing number of enum F\");\n")
+	assert.NotContains(code["F.java"], "UNRECOGNIZED")
+	assert.Contains(code["M.java"], "            if (e == E.UNRECOGNIZED) {\n                E.setNextUnrecognizedNumber(eUnrecognizedNumber);\n            }\n")
+	assert.Contains(code["M.java"], "                    eUnrecognizedNumber = e == E.UNRECOGNIZED ? E.takeLastUnrecognizedNumber() : -1;\n")
+	assert.Contains(code["M.java"], "    public int getEUnrecognizedNumber() { return eUnrecognizedNumber; }\n")
+	assert.NotContains(code["M.java"], "fUnrecognizedNumber")
+	assert.Contains(code["E.php"], "                            $this->enumValue = $number;\n")
+	assert.Contains(code["E.php"], "    public function isRecognized() {\n")
+	assert.Contains(code["F.php"], "throw new BreezeException('unknown enum number ' . $number);")
+	assert.Contains(code["k.breeze.h"], "	enum EE : int32_t {\n")
+	assert.Contains(code["k.breeze.cpp"], "			this->value_ = static_cast<EE>(number);\n")
+	assert.Contains(code["e.lua"], "function _M.is_recognized(self)\n")
+	assert.NotContains(code["f.lua"], "is_recognized")
+
+	// unknown numbers are kept by go code, and encoded unchanged
+	dir := generateGoModule(t, files)
+	if err := ioutil.WriteFile(filepath.Join(dir, "go", "test", "k", "k_test.go"), []byte(keepUnknownEnumTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goRun(t, dir, "test", "./go/test/k")
+}
+
+const keepUnknownEnumTest = `package k
+
+import (
+	"bytes"
+	"testing"
+
+	breeze "github.com/weibreeze/breeze-go"
+)
+
+func TestUnknownEnum(t *testing.T) {
+	e, f := E(7), F(7)
+	buf := breeze.NewBuffer(64)
+	if err := breeze.WriteValue(buf, &M{E: &e}); err != nil {
+		t.Fatal(err)
+	}
+	m := &M{}
+	if _, err := breeze.ReadValue(breeze.CreateBuffer(buf.Bytes()), m); err != nil {
+		t.Fatal(err)
+	}
+	if m.E == nil || *m.E != 7 || m.E.IsRecognized() || !EA.IsRecognized() {
+		t.Fatalf("unknown number is not kept: %v", m.E)
+	}
+	reencoded := breeze.NewBuffer(64)
+	if err := breeze.WriteValue(reencoded, m); err != nil || !bytes.Equal(buf.Bytes(), reencoded.Bytes()) {
+		t.Errorf("unknown number is not encoded unchanged: %v", err)
+	}
+
+	buf = breeze.NewBuffer(64)
+	if err := breeze.WriteValue(buf, &M{F: &f}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := breeze.ReadValue(breeze.CreateBuffer(buf.Bytes()), &M{}); err == nil {
+		t.Errorf("unknown number of enum without keep_unknown_enum should fail")
+	}
+}
+`
+
+func TestEnumNames(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{"n.breeze": "package test.n;\nenum Color {\n    BLUE = 3;\n    RED = 1;\n}\n"}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{})
+	assert.Nil(err)
+	assert.Contains(code["Color.java"], "    public int getNumber() { return number; }\n")
+	assert.Contains(code["Color.java"], "    public static Color valueOf(int number) {\n")
+	assert.Contains(code["Color.php"], "    private static $names = [1 => 'RED', 3 => 'BLUE'];\n")
+	assert.Contains(code["Color.php"], "    public static function fromName($name) {\n")
+	assert.Contains(code["n.breeze.h"], "	static bool from_name(const std::string &name, EColor &value);\n")
+	assert.Contains(code["n.breeze.cpp"], "	static const std::vector<EColor> values{RED, BLUE};\n")
+	assert.Contains(code["color.lua"], "_M.values = {RED, BLUE}\n")
+	assert.Contains(code["n.go"], "var ColorValues = []Color{ColorRED, ColorBLUE}\n")
+
+	dir := generateGoModule(t, files)
+	if err := ioutil.WriteFile(filepath.Join(dir, "go", "test", "n", "n_test.go"), []byte(enumNamesTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goRun(t, dir, "test", "./go/test/n")
+}
+
+const enumNamesTest = `package n
+
+import "testing"
+
+func TestNames(t *testing.T) {
+	if ColorBLUE.String() != "BLUE" || Color(2).String() != "2" {
+		t.Errorf("wrong names: %s, %s", ColorBLUE, Color(2))
+	}
+	if c, err := ParseColor("RED"); err != nil || c != ColorRED {
+		t.Errorf("parse fail: %v, %v", c, err)
+	}
+	if _, err := ParseColor("GREEN"); err == nil {
+		t.Errorf("unknown name should fail")
+	}
+	if len(ColorValues) != 2 || ColorValues[0] != ColorRED || ColorValues[1] != ColorBLUE {
+		t.Errorf("wrong values: %v", ColorValues)
+	}
+}
+`
+
+func TestWithTests(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{
+		"t.breeze": "package test.t;\noption with_tests = true;\nenum E {\n    A = 1;\n    B = 2;\n}\n" +
+			"message M {\n    bool b = 1;\n    string s = 2;\n    byte by = 3;\n    bytes bs = 4;\n    int16 i16 = 5;\n    int32 i32 = 6;\n" +
+			"    int64 i64 = 7;\n    float32 f32 = 8;\n    float64 f64 = 9;\n    E e = 10;\n    M next = 11;\n    test.o.O o = 12;\n" +
+			"    array<map<string, array<M>>> nested = 13;\n    map<int64, E> es = 14;\n    map<string, map<int32, bytes>> mb = 15;\n}\n",
+		"o.breeze": "package test.o;\nmessage O {\n    string name = 1;\n}\n",
+	}
+	// java tests are generated into their own directory, so main sources do not depend on JUnit
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "java"})
+	assert.Nil(err)
+	assert.NotContains(code, "TBreezeTest.java")
+	code, _, err = generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go,java,php", Options: map[string]string{"java.test_dir": "../../test/java"}})
+	assert.Nil(err)
+	assert.Contains(code["t_test.go"], "func TestMRoundTrip(t *testing.T) {\n")
+	assert.Contains(code["t_test.go"], "func BenchmarkMWrite(b *testing.B) {\n")
+	assert.Contains(code["t_test.go"], "func BenchmarkMRead(b *testing.B) {\n")
+	assert.Contains(code["t_test.go"], "	m.O = &test_o.O{}\n")
+	assert.NotContains(code, "o_test.go")
+	java := code["../../test/java/TBreezeTest.java"]
+	assert.Contains(java, "    @Test\n    public void testMRoundTrip() throws Exception {\n")
+	assert.Contains(java, "import test.o.O;\n")
+	assert.Contains(java, "        E f10 = new E[]{E.A, E.B}[r.nextInt(2)];\n        m.setE(f10);\n")
+	assert.Contains(java, "            M f11 = randomM(r, depth + 1);\n")
+	php := code["TBreezeTest.php"]
+	assert.Contains(php, "class TBreezeTest extends TestCase {\n")
+	assert.Contains(php, "use Test\\O\\O;\n")
+	assert.Contains(php, "            $this->assertEquals($message, $decoded);\n")
+	assert.NotContains(php, "TypeString")
+
+	dir := generateGoModule(t, files)
+	goRun(t, dir, "test", "-bench", ".", "-benchtime", "1x", "./go/test/t")
+}
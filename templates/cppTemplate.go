@@ -35,6 +35,10 @@ func (ct *CppTemplate) Name() string {
 	return Cpp
 }
 
+func (ct *CppTemplate) Options() []*core.Option {
+	return []*core.Option{keepUnknownEnumOption}
+}
+
 func (ct *CppTemplate) GenerateCode(schema *core.Schema, context *core.Context) (contents map[string][]byte, err error) {
 	headerBuf := &bytes.Buffer{}
 	contents = make(map[string][]byte)
@@ -58,11 +62,8 @@ func (ct *CppTemplate) generateHeader(schema *core.Schema, buf *bytes.Buffer) er
 			"\n#ifndef BREEZE_CPP_" + defineName + "_H\n" +
 				"#define BREEZE_CPP_" + defineName + "_H\n\n" +
 				"#include \"serialize/breeze.h\"\n\n")
-		var ml []*core.Message
-		for _, message := range schema.Messages {
-			ml = append(ml, message)
-		}
-		sort.Sort(MessageList(ml))
+		ml := sortMessages(schema)
+		sort.Stable(MessageList(ml))
 		for _, message := range ml {
 			if err := ct.generateHeaderClass(message, buf); err != nil {
 				return err
@@ -81,7 +82,11 @@ func (ct *CppTemplate) generateHeaderClass(message *core.Message, buf *bytes.Buf
 	}
 	buf.WriteString("public:\n")
 	if message.IsEnum {
-		buf.WriteString("	enum E" + message.Name + " {\n")
+		if keepUnknownEnum(message) { // fixed underlying type, so unknown numbers can be kept
+			buf.WriteString("	enum E" + message.Name + " : int32_t {\n")
+		} else {
+			buf.WriteString("	enum E" + message.Name + " {\n")
+		}
 		fields := sortEnumValues(message)
 		for _, v := range fields {
 			buf.WriteString("		" + v.Name + " = " + strconv.Itoa(v.Index) + ",\n")
@@ -115,6 +120,12 @@ func (ct *CppTemplate) generateHeaderClass(message *core.Message, buf *bytes.Buf
 			"	void set_name(const std::string &name) override;\n\n")
 	if message.IsEnum {
 		buf.WriteString("	int read_enum(BytesBuffer *) override;\n\n")
+		if keepUnknownEnum(message) {
+			buf.WriteString("	bool is_recognized() const;\n\n")
+		}
+		buf.WriteString("	std::string to_string() const;\n\n" +
+			"	static bool from_name(const std::string &name, E" + message.Name + " &value);\n\n" +
+			"	static const std::vector<E" + message.Name + "> &values();\n\n")
 	}
 	buf.WriteString(
 		"private:\n" +
@@ -127,15 +138,15 @@ func (ct *CppTemplate) generateCpp(schema *core.Schema, buf *bytes.Buffer) error
 	if len(schema.Messages) > 0 {
 		writeGenerateComment(buf, schema.Name)
 		buf.WriteString("\n#include \"serialize/" + schema.Name + ".h\"\n\n")
-		var ml []*core.Message
-		for _, message := range schema.Messages {
-			ml = append(ml, message)
-		}
-		sort.Sort(MessageList(ml))
+		ml := sortMessages(schema)
+		sort.Stable(MessageList(ml))
 		for _, message := range ml {
 			ct.generateMethodConstructor(schema, message, buf)
 			ct.generateMethodWriteTo(message, buf)
 			ct.generateMethodReadFrom(message, buf)
+			if message.IsEnum {
+				ct.generateEnumNames(message, buf)
+			}
 			buf.WriteString(
 				"std::string " + message.Name + "::get_name() const { return schema_->name_; }\n\n" +
 					"std::string " + message.Name + "::get_alias() { return schema_->alias_; }\n\n" +
@@ -241,12 +252,66 @@ func (ct *CppTemplate) generateMethodReadFrom(message *core.Message, buf *bytes.
 				"			this->value_ = " + v.Name + ";\n" +
 				"			break;\n")
 		}
-		buf.WriteString("		default:\n" +
-			"			return -1;\n" +
-			"	}\n" +
-			"	return 0;\n" +
-			"}\n\n")
+		if keepUnknownEnum(message) {
+			buf.WriteString("		default: // keep unknown number, it is written unchanged\n" +
+				"			this->value_ = static_cast<E" + message.Name + ">(number);\n" +
+				"			break;\n" +
+				"	}\n" +
+				"	return 0;\n" +
+				"}\n\n")
+			buf.WriteString("bool " + message.Name + "::is_recognized() const {\n" +
+				"	switch (this->value_) {\n")
+			for _, v := range fields {
+				buf.WriteString("		case " + v.Name + ":\n")
+			}
+			buf.WriteString("			return true;\n" +
+				"		default:\n" +
+				"			return false;\n" +
+				"	}\n" +
+				"}\n\n")
+		} else {
+			buf.WriteString("		default:\n" +
+				"			return -1;\n" +
+				"	}\n" +
+				"	return 0;\n" +
+				"}\n\n")
+		}
+	}
+}
+
+// generateEnumNames generates the name lookup of enum values and the values ordered by number
+func (ct *CppTemplate) generateEnumNames(message *core.Message, buf *bytes.Buffer) {
+	values := sortEnumValues(message)
+	buf.WriteString("std::string " + message.Name + "::to_string() const {\n" +
+		"	switch (this->value_) {\n")
+	for _, v := range values {
+		buf.WriteString("		case " + v.Name + ":\n" +
+			"			return \"" + v.Name + "\";\n")
+	}
+	buf.WriteString("		default:\n" +
+		"			return std::to_string(int32_t(this->value_));\n" +
+		"	}\n" +
+		"}\n\n")
+	buf.WriteString("bool " + message.Name + "::from_name(const std::string &name, " + message.Name + "::E" + message.Name + " &value) {\n")
+	for _, v := range values {
+		buf.WriteString("	if (name == \"" + v.Name + "\") {\n" +
+			"		value = " + v.Name + ";\n" +
+			"		return true;\n" +
+			"	}\n")
+	}
+	buf.WriteString("	return false;\n" +
+		"}\n\n")
+	buf.WriteString("const std::vector<" + message.Name + "::E" + message.Name + "> &" + message.Name + "::values() {\n" +
+		"	static const std::vector<E" + message.Name + "> values{")
+	for i, v := range values {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(v.Name)
 	}
+	buf.WriteString("};\n" +
+		"	return values;\n" +
+		"}\n\n")
 }
 
 func (ct *CppTemplate) getTypeString(tp *core.Type) string {
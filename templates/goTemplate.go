@@ -2,11 +2,14 @@ package templates
 
 import (
 	"bytes"
-	"crypto/md5"
 	"fmt"
+	"io/ioutil"
+	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/weibreeze/breeze-generator/core"
 )
@@ -25,6 +28,30 @@ var (
 		core.Array:   {typeString: "[]"},
 		core.Map:     {typeString: "map["},
 	}
+
+	// suffixes of go file names which are not built normally: test files, GOOS and GOARCH
+	goFileSuffixes = map[string]bool{
+		"test": true, "aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true, "illumos": true,
+		"ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true, "openbsd": true, "plan9": true, "solaris": true,
+		"wasip1": true, "windows": true, "zos": true, "386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true,
+		"arm64": true, "arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true, "mips64le": true,
+		"mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true, "riscv": true, "riscv64": true,
+		"s390": true, "s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+	}
+
+	// names which import aliases can not use: keywords, predeclared identifiers, and packages or variables in generated code
+	goReservedNames = map[string]bool{
+		"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true, "defer": true, "else": true,
+		"fallthrough": true, "for": true, "func": true, "go": true, "goto": true, "if": true, "import": true, "interface": true,
+		"map": true, "package": true, "range": true, "return": true, "select": true, "struct": true, "switch": true, "type": true, "var": true,
+		"bool": true, "byte": true, "error": true, "float32": true, "float64": true, "int": true, "int16": true, "int32": true,
+		"int64": true, "string": true, "true": true, "false": true, "nil": true, "append": true, "cap": true, "delete": true,
+		"len": true, "make": true, "new": true, "panic": true, "recover": true,
+		"breeze": true, "bytes": true, "context": true, "errors": true, "fmt": true, "json": true, "motan": true, "sort": true,
+		"strconv": true, "sync": true, "testing": true, "rand": true, "buf": true, "err": true, "result": true, "other": true,
+		"cloned": true, "message": true, "decoded": true, "data": true, "depth": true, "time": true, "motancore": true,
+		"request": true, "response": true, "timer": true, "callback": true,
+	}
 )
 
 type goTypeInfo struct {
@@ -42,13 +69,47 @@ func (gt *GoTemplate) Name() string {
 	return Go
 }
 
-//GenerateCode : generate golang code, one schema one file
+//Options : options accepted by golang template
+func (gt *GoTemplate) Options() []*core.Option {
+	return []*core.Option{
+		{Name: "package", Key: core.GoPackage, Description: "go import path of schema, and optional package name after `;` or blank, such as `github.com/a/b/demo;demopb`"},
+		{Name: "package_prefix", Key: core.GoPackagePrefix, Description: "prefix of go import path of generated packages"},
+		withPackageDirOption,
+		withDescriptorOption,
+		keepUnknownEnumOption,
+		{Name: "json_tag", Key: core.GoJSONTag, Description: "json tag of struct fields: original, snake, camel or none"},
+		{Name: "reuse", Key: core.GoReuse, Description: "decode messages in place reusing memory of fields, and generate pools and benchmarks of messages"},
+		withTestsOption,
+		{Name: "motan", Key: core.GoMotan, Description: "generate adapters of services over the client and provider of motan-go"},
+		{Name: "split_files", Key: core.GoSplitFiles, Description: "generate one file for each message, enum and service, and `<schema>_init.go` for registrations of schemas"},
+	}
+}
+
+//CacheKey : the go import path of output directory resolved by go.mod, generated imports depend on it if go_package is not set
+func (gt *GoTemplate) CacheKey(context *core.Context) string {
+	return gt.outputImportPath(context)
+}
+
+//GenerateCode : generate golang code, one schema one file, or one file for each message, enum and service if option go_split_files is true
 func (gt *GoTemplate) GenerateCode(schema *core.Schema, context *core.Context) (contents map[string][]byte, err error) {
+	contents = make(map[string][]byte)
+	fileName := goSchemaFileName(schema)
+	split, _ := strconv.ParseBool(schema.Options[core.GoSplitFiles])
+	importPath, _ := gt.goPackage(schema.Package, schema, context)
+	owners := gt.goFileOwners(context)[importPath]
+	addFile := func(file string, content []byte) error {
+		file = withPackageDir(file, schema, false)
+		if len(owners[file]) > 1 {
+			return fmt.Errorf("go file %s is generated by both %s", file, strings.Join(owners[file], " and "))
+		}
+		contents[file] = content
+		return nil
+	}
 	buf := &bytes.Buffer{}
 	importStr := make([]string, 0, 8)
-	importStr = append(importStr, "github.com/weibreeze/breeze-go")
 	messages := sortMessages(schema)
 	for _, message := range messages {
+		importStr = append(importStr, "github.com/weibreeze/breeze-go")
 		if message.IsEnum {
 			importStr, err = gt.generateEnum(schema, message, context, buf, importStr)
 		} else {
@@ -57,50 +118,77 @@ func (gt *GoTemplate) GenerateCode(schema *core.Schema, context *core.Context) (
 		if err != nil {
 			return nil, err
 		}
+		if split {
+			if err = addFile(goFileName(message.Name), gt.goFile(schema, context, importStr, buf)); err != nil {
+				return nil, err
+			}
+			buf, importStr = &bytes.Buffer{}, make([]string, 0, 8)
+		}
 	}
-	if len(schema.Services) > 0 {
-		for _, service := range schema.Services {
-			importStr, err = gt.generateService(schema, service, context, buf, importStr)
-			if err != nil {
+	for _, service := range sortServices(schema) {
+		importStr, err = gt.generateService(schema, service, context, buf, importStr)
+		if err != nil {
+			return nil, err
+		}
+		if split {
+			if err = addFile(goFileName(service.Name), gt.goFile(schema, context, importStr, buf)); err != nil {
 				return nil, err
 			}
+			buf, importStr = &bytes.Buffer{}, make([]string, 0, 8)
 		}
 	}
-	content := &bytes.Buffer{}
-	writeGenerateComment(content, schema.Name)
-	pkgIndex := strings.LastIndex(schema.Package, ".")
-	pkg := "\npackage " + schema.Package[pkgIndex+1:] + "\n\n"
-	content.WriteString(pkg)
-	gt.writeGoImport(importStr, content)
-	content.Write(buf.Bytes())
 
 	//init method
 	if len(messages) > 0 {
-		content.WriteString("\n")
+		importStr = append(importStr, "github.com/weibreeze/breeze-go")
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
 		for _, message := range messages {
-			content.WriteString("var " + gt.schemaName(message.Name) + "  *breeze.Schema\n")
+			buf.WriteString("var " + gt.schemaName(message.Name) + "  *breeze.Schema\n")
 		}
-		content.WriteString("\nfunc init() {\n")
+		buf.WriteString("\nfunc init() {\n")
 		for _, message := range messages {
-			content.WriteString("	" + gt.schemaName(message.Name) + " = &breeze.Schema{Name: \"" + schema.OrgPackage + "." + message.Name)
+			buf.WriteString("	" + gt.schemaName(message.Name) + " = &breeze.Schema{Name: \"" + schema.OrgPackage + "." + message.Name)
 			if message.Alias != "" {
-				content.WriteString("\", Alias: \"" + message.Alias)
+				buf.WriteString("\", Alias: \"" + message.Alias)
 			}
-			content.WriteString("\"}\n")
+			buf.WriteString("\"}\n")
 			if message.IsEnum {
-				content.WriteString("	" + gt.schemaName(message.Name) + ".PutFields(&breeze.Field{Index: 1, Name: \"enumNumber\", Type: \"int32\"})\n")
+				buf.WriteString("	" + gt.schemaName(message.Name) + ".PutFields(&breeze.Field{Index: 1, Name: \"enumNumber\", Type: \"int32\"})\n")
 			} else { // message
 				for _, field := range sortFields(message) {
-					content.WriteString("	" + gt.schemaName(message.Name) + ".PutFields(&breeze.Field{Index: " + strconv.Itoa(field.Index) + ", Name: \"" + field.Name + "\", Type: \"" + field.Type.TypeString + "\"})\n")
+					buf.WriteString("	" + gt.schemaName(message.Name) + ".PutFields(&breeze.Field{Index: " + strconv.Itoa(field.Index) + ", Name: \"" + field.Name + "\", Type: \"" + field.Type.TypeString + "\"})\n")
 				}
 			}
-			content.WriteString("\n")
+			buf.WriteString("\n")
 		}
-		content.Truncate(content.Len() - 1)
-		content.WriteString("}\n")
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteString("}\n")
 	}
+	desc, err := binaryDescriptor(schema, context)
+	if err != nil {
+		return nil, err
+	}
+	if desc != nil {
+		gt.writeDescriptor(schema, desc, buf)
+	}
+	if !split {
+		err = addFile(fileName+".go", gt.goFile(schema, context, importStr, buf))
+	} else if buf.Len() > 0 {
+		err = addFile(goInitFileName(fileName), gt.goFile(schema, context, importStr, buf))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if test := gt.generateTests(schema, context, messages); test != nil {
+		contents[withPackageDir(fileName+"_test.go", schema, false)] = test
+	}
+	return contents, nil
+}
 
-	contents = make(map[string][]byte)
+// goSchemaFileName returns the go file name of schema without suffix, such as `demo` for `com.weibo.demo.breeze`
+func goSchemaFileName(schema *core.Schema) string {
 	fileName := schema.Name
 	var index int
 	if index = strings.LastIndex(fileName, "."); index > -1 { //remove suffix of schema file.
@@ -109,86 +197,509 @@ func (gt *GoTemplate) GenerateCode(schema *core.Schema, context *core.Context) (
 			fileName = fileName[index+1:]
 		}
 	}
-	fileName = withPackageDir(fileName, schema, false)
-	contents[fileName+".go"] = content.Bytes()
-	return contents, nil
+	return fileName
 }
 
-var (
-	aliasImprotNameData = map[string]string{}
-	hashCounter         = 0
-)
+// goInitFileName returns the file holding registrations and descriptor of a split schema, such as `user_init.go` for `user.breeze`
+func goInitFileName(fileName string) string {
+	return goFileName(fileName + "_init")
+}
 
-func (gt *GoTemplate) getAliasImprotName(schema *core.Schema, importStr string, context *core.Context) string {
-	hint := filepath.Base(filepath.Dir(importStr)) + "_" + filepath.Base(importStr)
-	hash := ""
-	for k, v := range aliasImprotNameData {
-		if v == importStr {
-			return fmt.Sprintf("p_%s_%s", k, hint)
+// goFileOwners returns the messages, enums, services and schemas generating each go file of all schemas in context,
+// keyed by go import path and file path. files of schemas in the same go package are checked together,
+// and a file with more than one owner is a conflict. they are resolved once for each context.
+func (gt *GoTemplate) goFileOwners(context *core.Context) map[string]map[string][]string {
+	return context.Value(goFileOwnersKey{}, func() interface{} {
+		owners := make(map[string]map[string][]string)
+		for _, schema := range context.SortedSchemas() {
+			importPath, _ := gt.goPackage(schema.Package, schema, context)
+			if owners[importPath] == nil {
+				owners[importPath] = make(map[string][]string)
+			}
+			files := owners[importPath]
+			add := func(file string, owner string) {
+				file = withPackageDir(file, schema, false)
+				files[file] = append(files[file], owner)
+			}
+			fileName := goSchemaFileName(schema)
+			if split, _ := strconv.ParseBool(schema.Options[core.GoSplitFiles]); !split {
+				add(fileName+".go", "schema "+schema.Name)
+				continue
+			}
+			for _, message := range sortMessages(schema) {
+				add(goFileName(message.Name), message.Name+" of "+schema.Name)
+			}
+			for _, service := range sortServices(schema) {
+				add(goFileName(service.Name), service.Name+" of "+schema.Name)
+			}
+			if withDescriptor, _ := strconv.ParseBool(schema.Options[core.WithDescriptor]); len(schema.Messages) > 0 || withDescriptor {
+				add(goInitFileName(fileName), "registrations of "+schema.Name)
+			}
 		}
+		return owners
+	}).(map[string]map[string][]string)
+}
+
+// goFileOwnersKey is the key of owners of go files of all schemas in context
+type goFileOwnersKey struct{}
+
+// goFile returns the content of a go file of schema with imports and body
+func (gt *GoTemplate) goFile(schema *core.Schema, context *core.Context, importStr []string, body *bytes.Buffer) []byte {
+	content := &bytes.Buffer{}
+	writeGenerateComment(content, schema.Name)
+	_, pkgName := gt.goPackage(schema.Package, schema, context)
+	content.WriteString("\npackage " + pkgName + "\n\n")
+	gt.writeGoImport(importStr, content)
+	content.Write(body.Bytes())
+	return content.Bytes()
+}
+
+// goFileName returns the file name of a message, enum or service such as `user_info.go` for `UserInfo`.
+// suffixes of test files and build constraints such as `_test` and `_linux` are followed by `_breeze`.
+func goFileName(name string) string {
+	name = snakeCase(name)
+	if goFileSuffixes[name[strings.LastIndex(name, "_")+1:]] && strings.Contains(name, "_") {
+		name += "_breeze"
 	}
+	return name + ".go"
+}
 
-	h := md5.New()
-	h.Write([]byte(importStr))
-	hash = fmt.Sprintf("%x", h.Sum(nil))[0:12]
+// generateTests generates the tests of messages in schema, it returns nil if there is no test.
+// round-trip tests and benchmarks of encoding and decoding are generated if option with_tests is true,
+// and benchmarks which compare decoding into new messages with decoding in place are generated for messages with option go_reuse.
+func (gt *GoTemplate) generateTests(schema *core.Schema, context *core.Context, messages []*core.Message) []byte {
+	buf := &bytes.Buffer{}
+	importStr := []string{"testing", "github.com/weibreeze/breeze-go"}
+	tests := withTests(schema)
+	for _, message := range messages {
+		if message.IsEnum {
+			continue
+		}
+		if tests {
+			importStr = gt.generateRoundTripTest(schema, message, context, buf, importStr)
+		}
+		if !reuseMessage(message) {
+			continue
+		}
+		var sample string
+		sample, importStr = gt.sampleMessage(message, schema.Package, schema, context, importStr, []string{schema.Package + "." + message.Name})
+		buf.WriteString("func Benchmark" + message.Name + "ReadFrom(b *testing.B) {\n	data := encode" + message.Name + "Sample(b)\n")
+		buf.WriteString("	b.ReportAllocs()\n	b.ResetTimer()\n	for i := 0; i < b.N; i++ {\n")
+		buf.WriteString("		if err := breeze.ReadByMessage(breeze.CreateBuffer(data), &" + message.Name + "{}); err != nil {\n			b.Fatal(err)\n		}\n	}\n}\n\n")
+		buf.WriteString("func Benchmark" + message.Name + "ReadFromInPlace(b *testing.B) {\n	data := encode" + message.Name + "Sample(b)\n")
+		buf.WriteString("	message := Acquire" + message.Name + "()\n	defer Release" + message.Name + "(message)\n	buf := breeze.CreateBuffer(data)\n")
+		buf.WriteString("	b.ReportAllocs()\n	b.ResetTimer()\n	for i := 0; i < b.N; i++ {\n		buf.SetRPos(0)\n		message.Reset()\n")
+		buf.WriteString("		if err := breeze.ReadByMessage(buf, message); err != nil {\n			b.Fatal(err)\n		}\n	}\n}\n\n")
+		buf.WriteString("func encode" + message.Name + "Sample(b *testing.B) []byte {\n	buf := breeze.NewBuffer(256)\n")
+		buf.WriteString("	if err := breeze.WriteValue(buf, &" + message.Name + sample + "); err != nil {\n		b.Fatal(err)\n	}\n	return buf.Bytes()\n}\n\n")
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	content := &bytes.Buffer{}
+	writeGenerateComment(content, schema.Name)
+	_, pkgName := gt.goPackage(schema.Package, schema, context)
+	content.WriteString("\npackage " + pkgName + "\n\n")
+	gt.writeGoImport(importStr, content)
+	buf.Truncate(buf.Len() - 1)
+	content.Write(buf.Bytes())
+	return content.Bytes()
+}
 
-	if v, ok := aliasImprotNameData[hash]; !ok {
-		aliasImprotNameData[hash] = importStr
-	} else {
-		if v != importStr {
-			hash += fmt.Sprintf("%d", hashCounter)
-			hashCounter++
+// generateRoundTripTest generates the test which encodes and decodes messages with pseudo-random values,
+// and benchmarks of encoding and decoding
+func (gt *GoTemplate) generateRoundTripTest(schema *core.Schema, message *core.Message, context *core.Context, buf *bytes.Buffer, importStr []string) []string {
+	importStr = append(importStr, "math/rand")
+	name := message.Name
+	buf.WriteString("// random" + name + " returns " + name + " with pseudo-random values, messages deeper than 2 are not set\n")
+	buf.WriteString("func random" + name + "(r *rand.Rand, depth int) *" + name + " {\n	m := &" + name + "{}\n")
+	for _, field := range sortFields(message) {
+		if containsMessage(field.Type, schema, context) {
+			buf.WriteString("	if depth < 2 {\n")
+			importStr = gt.randomValue(buf, field.Type, "m."+firstUpper(field.Name), "		", 1, schema, context, importStr)
+			buf.WriteString("	}\n")
+		} else {
+			importStr = gt.randomValue(buf, field.Type, "m."+firstUpper(field.Name), "	", 1, schema, context, importStr)
 		}
 	}
+	buf.WriteString("	return m\n}\n\n")
+
+	buf.WriteString("func Test" + name + "RoundTrip(t *testing.T) {\n	r := rand.New(rand.NewSource(1))\n	for i := 0; i < 20; i++ {\n")
+	buf.WriteString("		message := random" + name + "(r, 0)\n		buf := breeze.NewBuffer(256)\n")
+	buf.WriteString("		if err := breeze.WriteValue(buf, message); err != nil {\n			t.Fatal(err)\n		}\n")
+	buf.WriteString("		decoded := &" + name + "{}\n")
+	buf.WriteString("		if err := breeze.ReadByMessage(breeze.CreateBuffer(buf.Bytes()), decoded); err != nil {\n			t.Fatal(err)\n		}\n")
+	buf.WriteString("		if !decoded.Equal(message) {\n			t.Fatalf(\"decoded message is not equal, expect: %+v, actual: %+v\", message, decoded)\n		}\n	}\n}\n\n")
 
-	return fmt.Sprintf("p_%s_%s", hash, hint)
+	buf.WriteString("func Benchmark" + name + "Write(b *testing.B) {\n	message := random" + name + "(rand.New(rand.NewSource(1)), 0)\n")
+	buf.WriteString("	b.ReportAllocs()\n	b.ResetTimer()\n	for i := 0; i < b.N; i++ {\n")
+	buf.WriteString("		if err := breeze.WriteValue(breeze.NewBuffer(256), message); err != nil {\n			b.Fatal(err)\n		}\n	}\n}\n\n")
+	buf.WriteString("func Benchmark" + name + "Read(b *testing.B) {\n	buf := breeze.NewBuffer(256)\n")
+	buf.WriteString("	if err := breeze.WriteValue(buf, random" + name + "(rand.New(rand.NewSource(1)), 0)); err != nil {\n		b.Fatal(err)\n	}\n	data := buf.Bytes()\n")
+	buf.WriteString("	b.ReportAllocs()\n	b.ResetTimer()\n	for i := 0; i < b.N; i++ {\n")
+	buf.WriteString("		if err := breeze.ReadByMessage(breeze.CreateBuffer(data), &" + name + "{}); err != nil {\n			b.Fatal(err)\n		}\n	}\n}\n\n")
+	return importStr
 }
 
-func (gt *GoTemplate) getImportInfo(field *core.Field, importStrArr []string, context *core.Context, schema *core.Schema) (importStr []string, typeString string) {
-	return gt.getImportInfoByType(field.Type, importStrArr, context, schema)
+// randomValue writes the statements which set name to a pseudo-random value of type tp by r.
+// messages declared in other schemas are empty, because their random functions are not accessible.
+func (gt *GoTemplate) randomValue(buf *bytes.Buffer, tp *core.Type, name string, blank string, recursion int, schema *core.Schema, context *core.Context, importStr []string) []string {
+	recStr := strconv.Itoa(recursion)
+	var tpStr string
+	tpStr, importStr = gt.goTypeString(tp, schema, context, importStr)
+	switch tp.Number {
+	case core.Bool:
+		buf.WriteString(blank + name + " = r.Intn(2) == 1\n")
+	case core.String:
+		importStr = append(importStr, "strconv")
+		buf.WriteString(blank + name + " = strconv.FormatInt(r.Int63(), 36)\n")
+	case core.Byte:
+		buf.WriteString(blank + name + " = byte(r.Intn(256))\n")
+	case core.Bytes:
+		importStr = append(importStr, "strconv")
+		buf.WriteString(blank + name + " = []byte(strconv.FormatInt(r.Int63(), 36))\n")
+	case core.Int16, core.Int32:
+		buf.WriteString(blank + name + " = " + tpStr + "(r.Uint32())\n")
+	case core.Int64:
+		buf.WriteString(blank + name + " = int64(r.Uint64())\n")
+	case core.Float32:
+		buf.WriteString(blank + name + " = r.Float32()\n")
+	case core.Float64:
+		buf.WriteString(blank + name + " = r.NormFloat64()\n")
+	case core.Array:
+		valueTpStr, _ := gt.goTypeString(tp.ValueType, schema, context, nil)
+		buf.WriteString(blank + "for i" + recStr + " := r.Intn(3); i" + recStr + " > 0; i" + recStr + "-- {\n")
+		buf.WriteString(blank + "	var v" + recStr + " " + valueTpStr + "\n")
+		importStr = gt.randomValue(buf, tp.ValueType, "v"+recStr, blank+"	", recursion+1, schema, context, importStr)
+		buf.WriteString(blank + "	" + name + " = append(" + name + ", v" + recStr + ")\n" + blank + "}\n")
+	case core.Map:
+		valueTpStr, _ := gt.goTypeString(tp.ValueType, schema, context, nil)
+		buf.WriteString(blank + name + " = make(" + tpStr + ")\n")
+		buf.WriteString(blank + "for i" + recStr + " := r.Intn(3); i" + recStr + " > 0; i" + recStr + "-- {\n")
+		buf.WriteString(blank + "	var k" + recStr + " " + goTypes[tp.KeyType.Number].typeString + "\n")
+		importStr = gt.randomValue(buf, tp.KeyType, "k"+recStr, blank+"	", recursion+1, schema, context, importStr)
+		buf.WriteString(blank + "	var v" + recStr + " " + valueTpStr + "\n")
+		importStr = gt.randomValue(buf, tp.ValueType, "v"+recStr, blank+"	", recursion+1, schema, context, importStr)
+		buf.WriteString(blank + "	" + name + "[k" + recStr + "] = v" + recStr + "\n" + blank + "}\n")
+	case core.Msg:
+		if isEnum(tp, schema, context) {
+			values := tpStr[1:] + "Values"
+			buf.WriteString(blank + name + " = func() " + tpStr + " { v := " + values + "[r.Intn(len(" + values + "))]; return &v }()\n")
+		} else if message := schemaMessage(tp, schema); message != nil {
+			buf.WriteString(blank + name + " = random" + message.Name + "(r, depth+1)\n")
+		} else {
+			buf.WriteString(blank + name + " = &" + tpStr[1:] + "{}\n")
+		}
+	}
+	return importStr
 }
 
-func (gt *GoTemplate) getImportInfoByType(tp *core.Type, importStrArr []string, context *core.Context, schema *core.Schema) (importStr []string, typeString string) {
-	typeString = gt.getTypeString(tp)
-	if strings.Contains(typeString, "*") {
-		if len(importStrArr) > 0 {
-			pkgPath := importStrArr[len(importStrArr)-1]
-			if strings.Replace(pkgPath, "/", ".", -1) != schema.Package {
-				aliasPkg := gt.getAliasImprotName(schema, pkgPath, context)
-				pkgPath = aliasPkg + " " + pkgPath
-				importStrArr[len(importStrArr)-1] = pkgPath
-				if strings.Contains(typeString, ".") {
-					prefix := typeString[:strings.Index(typeString, "*")]
-					suffix := typeString[strings.LastIndex(typeString, ".")+1:]
-					typeString = prefix + "*" + aliasPkg + "." + suffix
+// sampleMessage returns the literal of a sample message declared in package pkg with all fields set,
+// except fields of messages in path, which are the outer messages of the sample
+func (gt *GoTemplate) sampleMessage(message *core.Message, pkg string, schema *core.Schema, context *core.Context, importStr []string, path []string) (string, []string) {
+	items := make([]string, 0, len(message.Fields))
+	for _, field := range sortFields(message) {
+		var value string
+		value, importStr = gt.sampleValue(qualifiedType(field.Type, pkg), schema, context, importStr, path)
+		if value != "nil" {
+			items = append(items, firstUpper(field.Name)+": "+value)
+		}
+	}
+	return "{" + strings.Join(items, ", ") + "}", importStr
+}
+
+// sampleValue returns the literal of a sample value of type tp in the code of schema, nil if it is a message in path.
+// messages of tp are full names or declared in schema.
+func (gt *GoTemplate) sampleValue(tp *core.Type, schema *core.Schema, context *core.Context, importStr []string, path []string) (string, []string) {
+	var tpStr, value, key string
+	tpStr, importStr = gt.goTypeString(tp, schema, context, importStr)
+	switch tp.Number {
+	case core.Bool:
+		return "true", importStr
+	case core.String:
+		return "\"breeze\"", importStr
+	case core.Bytes:
+		return "[]byte(\"breeze\")", importStr
+	case core.Float32, core.Float64:
+		return "1.5", importStr
+	case core.Byte, core.Int16, core.Int32, core.Int64:
+		return "100", importStr
+	case core.Array:
+		if value, importStr = gt.sampleValue(tp.ValueType, schema, context, importStr, path); value == "nil" {
+			return tpStr + "{}", importStr
+		}
+		return tpStr + "{" + value + ", " + value + "}", importStr
+	case core.Map:
+		key, importStr = gt.sampleValue(tp.KeyType, schema, context, importStr, path)
+		if value, importStr = gt.sampleValue(tp.ValueType, schema, context, importStr, path); value == "nil" {
+			return tpStr + "{}", importStr
+		}
+		return tpStr + "{" + key + ": " + value + "}", importStr
+	}
+	fullName := tp.Name
+	if strings.Index(fullName, ".") < 0 {
+		fullName = schema.Package + "." + fullName
+	}
+	message := context.GetMessage(fullName)
+	if message == nil {
+		return "nil", importStr
+	}
+	if message.IsEnum {
+		return "func() " + tpStr + " { v := " + tpStr[1:] + "(" + strconv.Itoa(sortEnumValues(message)[0].Index) + "); return &v }()", importStr
+	}
+	for _, name := range path {
+		if name == fullName {
+			return "nil", importStr
+		}
+	}
+	value, importStr = gt.sampleMessage(message, fullName[:strings.LastIndex(fullName, ".")], schema, context, importStr, append(path[:len(path):len(path)], fullName))
+	return "&" + tpStr[1:] + value, importStr
+}
+
+// qualifiedType returns tp with full names of messages, messages without package are declared in package pkg
+func qualifiedType(tp *core.Type, pkg string) *core.Type {
+	switch tp.Number {
+	case core.Array, core.Map:
+		qualified := *tp
+		qualified.ValueType = qualifiedType(tp.ValueType, pkg)
+		return &qualified
+	case core.Msg:
+		if !strings.Contains(tp.Name, ".") {
+			qualified := *tp
+			qualified.Name = pkg + "." + tp.Name
+			return &qualified
+		}
+	}
+	return tp
+}
+
+// importAlias returns the import alias of go package importPath, such as `java1_util` for `github.com/demo/java1/util`.
+// aliases are resolved among the go packages of all schemas in context, so they are stable across runs,
+// and a package has the same alias in all generated files. they are resolved once for each context.
+func (gt *GoTemplate) importAlias(importPath string, context *core.Context) string {
+	aliases := context.Value(goAliasesKey{}, func() interface{} {
+		outputImportPath := func() string { return gt.outputImportPath(context) }
+		schemas := context.SortedSchemas()
+		paths := make([]string, 0, len(schemas))
+		for _, s := range schemas {
+			p, _ := gt.goPackageByOptions(s.Package, s.Options, outputImportPath)
+			paths = append(paths, p)
+		}
+		paths = sortUnique(paths)
+		return &goAliases{paths: paths, aliases: goImportAliases(paths)}
+	}).(*goAliases)
+	if alias, ok := aliases.aliases[importPath]; ok {
+		return alias
+	}
+	// package of missing types
+	return goImportAliases(sortUnique(append([]string{importPath}, aliases.paths...)))[importPath]
+}
+
+// goAliasesKey is the key of import aliases of go packages of all schemas in context
+type goAliasesKey struct{}
+
+type goAliases struct {
+	paths   []string // sorted import paths of all schemas
+	aliases map[string]string
+}
+
+// goImportAliases returns unique aliases of sorted import paths. an alias is made of the fewest last elements of
+// its path which no other path ends with, at least two, such as `java1_util` for `java1/util`.
+// a number is appended if all elements are not enough, such as `a_b2` for `a/b` when `x/a_b` exists.
+func goImportAliases(paths []string) map[string]string {
+	elements := make(map[string][]string, len(paths))
+	counts := make(map[string]int, len(paths)*2) // how many paths can have the alias
+	for _, p := range paths {
+		items := strings.FieldsFunc(p, func(r rune) bool { return r == '/' })
+		for i, item := range items {
+			items[i] = strings.Map(func(r rune) rune {
+				if unicode.IsLetter(r) || unicode.IsDigit(r) {
+					return r
 				}
+				return '_'
+			}, item)
+		}
+		elements[p] = items
+		for k := 1; k <= len(items); k++ {
+			counts[goAlias(items[len(items)-k:])]++
+		}
+	}
+	aliases := make(map[string]string, len(paths))
+	used := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		items := elements[p]
+		var alias string
+		from := 2
+		if len(items) < from {
+			from = len(items)
+		}
+		for k := from; k <= len(items); k++ {
+			candidate := goAlias(items[len(items)-k:])
+			if counts[candidate] == 1 && !used[candidate] && !goReservedNames[candidate] && len(candidate) > 1 { // receivers are single letters
+				alias = candidate
+				break
 			}
-		} else {
-			if strings.Contains(typeString, ".") {
-				prefix := typeString[:strings.Index(typeString, "*")+1]
-				suffix := typeString[strings.LastIndex(typeString, ".")+1:]
-				typeString = prefix + suffix
+		}
+		for i := 2; alias == ""; i++ {
+			candidate := goAlias(items) + strconv.Itoa(i)
+			if counts[candidate] == 0 && !used[candidate] {
+				alias = candidate
 			}
 		}
+		used[alias] = true
+		aliases[p] = alias
+	}
+	return aliases
+}
+
+// goAlias joins elements of import path as an identifier
+func goAlias(items []string) string {
+	alias := strings.Join(items, "_")
+	if alias == "" || unicode.IsDigit(rune(alias[0])) {
+		alias = "_" + alias
 	}
-	if strings.HasPrefix(typeString, "**") {
-		typeString = typeString[1:]
-	} else if strings.HasPrefix(typeString, "*[]") {
-		typeString = typeString[1:]
-	} else if strings.HasPrefix(typeString, "*map") {
-		typeString = typeString[1:]
+	return alias
+}
+
+// goPackage returns the go import path and package name of breeze package pkg.
+// option go_package of the schema declaring pkg is used if set, such as `github.com/a/b/demo` or `github.com/a/b/demo;demopb`.
+// the package name is the last element of import path if not set.
+// otherwise the import path is the package path with prefix go_package_prefix,
+// or with the import path of output directory, which is inferred from the nearest go.mod above it.
+func (gt *GoTemplate) goPackage(pkg string, schema *core.Schema, context *core.Context) (importPath string, name string) {
+	options := schema.Options
+	if pkg != schema.Package {
+		for _, s := range context.SortedSchemas() {
+			if s.Package == pkg {
+				options = s.Options
+				break
+			}
+		}
+	}
+	return gt.goPackageByOptions(pkg, options, func() string { return gt.outputImportPath(context) })
+}
+
+// goPackageByOptions returns the go import path and package name of breeze package pkg by options of the schema declaring it.
+// outputImportPath is only called if neither go_package nor go_package_prefix is set.
+func (gt *GoTemplate) goPackageByOptions(pkg string, options map[string]string, outputImportPath func() string) (importPath string, name string) {
+	if goPackage := options[core.GoPackage]; goPackage != "" {
+		importPath = goPackage
+		// `;` ends a line of schema file, so the package name can be separated by blank too
+		if index := strings.IndexAny(goPackage, "; \t"); index > -1 {
+			importPath, name = goPackage[:index], strings.TrimSpace(goPackage[index+1:])
+		}
+		if name == "" {
+			name = strings.NewReplacer("-", "_", ".", "_").Replace(path.Base(importPath))
+		}
+		return importPath, name
+	}
+	pkgPath := strings.ReplaceAll(pkg, ".", "/")
+	name = pkg[strings.LastIndex(pkg, ".")+1:]
+	if prefix := options[core.GoPackagePrefix]; prefix != "" {
+		return strings.TrimSuffix(prefix, "/") + "/" + pkgPath, name
+	}
+	if module := outputImportPath(); module != "" {
+		if b, _ := strconv.ParseBool(options[core.WithPackageDir]); b {
+			return module + "/" + pkgPath, name
+		}
+		return module, name
+	}
+	return pkgPath, name
+}
+
+// outputImportPath returns the go import path of the output directory according to the nearest go.mod above it,
+// empty if the output is not a directory or no go.mod is found. it is resolved once for each context.
+func (gt *GoTemplate) outputImportPath(context *core.Context) string {
+	return context.Value(goOutputImportPathKey{}, func() interface{} {
+		return gt.findOutputImportPath(context)
+	}).(string)
+}
+
+// goOutputImportPathKey is the key of the go import path of output directory in context
+type goOutputImportPathKey struct{}
+
+// findOutputImportPath walks up from the output directory to find go.mod
+func (gt *GoTemplate) findOutputImportPath(context *core.Context) string {
+	output, ok := context.Output.(core.DirectoryOutput)
+	if !ok {
+		return ""
+	}
+	dir, err := filepath.Abs(output.Dir(gt.Name()))
+	if err != nil {
+		return ""
+	}
+	for modDir := dir; ; modDir = filepath.Dir(modDir) {
+		if content, err := ioutil.ReadFile(filepath.Join(modDir, "go.mod")); err == nil {
+			module := goModulePath(content)
+			rel, err := filepath.Rel(modDir, dir)
+			if module == "" || err != nil {
+				return ""
+			}
+			if rel == "." {
+				return module
+			}
+			return module + "/" + filepath.ToSlash(rel)
+		}
+		if filepath.Dir(modDir) == modDir {
+			return ""
+		}
 	}
-	return importStrArr, typeString
 }
+
+// goModulePath returns the module path declared in go.mod
+func goModulePath(content []byte) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module") {
+			module := strings.TrimSpace(line[len("module"):])
+			if index := strings.Index(module, "//"); index > -1 {
+				module = strings.TrimSpace(module[:index])
+			}
+			return strings.Trim(module, "\"`")
+		}
+	}
+	return ""
+}
+
+// goTypeString returns the go type of tp, imports of message types in other go packages are appended to importStr
+func (gt *GoTemplate) goTypeString(tp *core.Type, schema *core.Schema, context *core.Context, importStr []string) (string, []string) {
+	var typeString string
+	switch tp.Number {
+	case core.Array:
+		typeString, importStr = gt.goTypeString(tp.ValueType, schema, context, importStr)
+		return goTypes[tp.Number].typeString + typeString, importStr
+	case core.Map:
+		typeString, importStr = gt.goTypeString(tp.ValueType, schema, context, importStr)
+		return goTypes[tp.Number].typeString + goTypes[tp.KeyType.Number].typeString + "]" + typeString, importStr
+	case core.Msg:
+		pkg, name := schema.Package, tp.Name
+		if index := strings.LastIndex(tp.Name, "."); index > -1 {
+			pkg, name = tp.Name[:index], tp.Name[index+1:]
+		}
+		if pkg != schema.Package {
+			importPath, _ := gt.goPackage(pkg, schema, context)
+			if selfPath, _ := gt.goPackage(schema.Package, schema, context); importPath != selfPath {
+				alias := gt.importAlias(importPath, context)
+				importStr = append(importStr, alias+" "+importPath)
+				return "*" + alias + "." + name, importStr
+			}
+		}
+		return "*" + name, importStr
+	}
+	return goTypes[tp.Number].typeString, importStr
+}
+
 func (gt *GoTemplate) generateMessage(schema *core.Schema, message *core.Message, context *core.Context, buf *bytes.Buffer, importStr []string) ([]string, error) {
 	buf.WriteString("type " + message.Name + " struct {\n")
 	fields := sortFields(message) //sorted fields
-	var tps []string
 	for _, field := range fields {
-		importStr0 := gt.getTypeImport(schema, field.Type, tps, context)
-		importStr0, typeString := gt.getImportInfo(field, importStr0, context, schema)
-		importStr = append(importStr, importStr0...)
-		buf.WriteString("	" + firstUpper(field.Name) + " " + typeString + "\n")
+		var typeString string
+		typeString, importStr = gt.goTypeString(field.Type, schema, context, importStr)
+		tag, err := gt.fieldTag(message, field)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("	" + firstUpper(field.Name) + " " + typeString + tag + "\n")
 	}
 	buf.WriteString("}\n\n")
 
@@ -224,8 +735,14 @@ func (gt *GoTemplate) generateMessage(schema *core.Schema, message *core.Message
 	buf.WriteString("	})\n}\n\n")
 
 	//readFrom
+	reuse := reuseMessage(message)
 	buf.WriteString(funcName + " ReadFrom(buf *breeze.Buffer) error {\n		return breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {\n		switch index {\n")
 	for _, field := range fields {
+		if reuse { // decode in place
+			buf.WriteString("		case " + strconv.Itoa(field.Index) + ":\n")
+			gt.readInPlace(buf, field.Type, shortName+"."+firstUpper(field.Name), true, false, "			", 1, schema, context)
+			continue
+		}
 		fieldName := shortName + "." + firstUpper(field.Name)
 		buf.WriteString("		case " + strconv.Itoa(field.Index) + ":\n")
 		tp := field.Type
@@ -238,26 +755,10 @@ func (gt *GoTemplate) generateMessage(schema *core.Schema, message *core.Message
 			case core.Map:
 				gt.readMap(buf, tp, fieldName, 1, schema, context)
 			case core.Msg:
-				tpStr := gt.getTypeString(tp)[1:]
-				if strings.Contains(tp.TypeString, ".") {
-					s := strings.Replace(tp.TypeString, ".", "/", -1)
-					importStr := filepath.Dir(s)
-					basename := filepath.Base(s)
-					isInSelfPackage := strings.Replace(importStr, "/", ".", -1) == schema.Package
-					prefix := ""
-					if context.Options != nil {
-						prefix = context.Options[core.GoPackagePrefix]
-						prefix = strings.TrimSuffix(prefix, "/") + "/"
-						importStr = prefix + importStr
-					}
-					if !isInSelfPackage {
-						tpStr = gt.getAliasImprotName(schema, importStr, context) + "." + basename
-					} else {
-						tpStr = basename
-					}
-				}
+				tpStr, _ := gt.goTypeString(tp, schema, context, nil)
+				tpStr = tpStr[1:]
 				if isEnum(field.Type, schema, context) {
-					buf.WriteString("			var value " + tpStr + "\n			result, err := breeze.ReadByEnum(buf, value, true)\n			if err == nil {\n")
+					buf.WriteString("			var value " + tpStr + "\n			var result interface{}\n			if result, err = breeze.ReadByEnum(buf, value, true); err == nil {\n")
 					buf.WriteString("				" + fieldName + " = result.(*" + tpStr + ")\n			}\n")
 				} else {
 					buf.WriteString("			" + fieldName + " = &" + tpStr + "{}\n")
@@ -268,11 +769,287 @@ func (gt *GoTemplate) generateMessage(schema *core.Schema, message *core.Message
 	}
 	buf.WriteString("		default: //skip unknown field\n			_, err = breeze.ReadValue(buf, nil)\n		}\n		return err\n	})\n}\n\n")
 
+	//equal, clone and reset
+	buf.WriteString("// Equal returns whether " + shortName + " and other are deeply equal, nil and empty maps, arrays or bytes are equal\n")
+	buf.WriteString(funcName + " Equal(other *" + message.Name + ") bool {\n	if " + shortName + " == other {\n		return true\n	}\n")
+	buf.WriteString("	if " + shortName + " == nil || other == nil {\n		return false\n	}\n")
+	for _, field := range fields {
+		importStr = gt.equalValue(buf, field.Type, shortName+"."+firstUpper(field.Name), "other."+firstUpper(field.Name), "	", 1, schema, context, importStr)
+	}
+	buf.WriteString("	return true\n}\n\n")
+	buf.WriteString("// Clone returns a deep copy of " + shortName + "\n")
+	buf.WriteString(funcName + " Clone() *" + message.Name + " {\n	if " + shortName + " == nil {\n		return nil\n	}\n	cloned := *" + shortName + "\n")
+	for _, field := range fields {
+		if field.Type.Number == core.Bytes || field.Type.Number >= core.Map { // other fields are copied by value
+			gt.cloneValue(buf, field.Type, shortName+"."+firstUpper(field.Name), "cloned."+firstUpper(field.Name), "	", 1, schema, context)
+		}
+	}
+	buf.WriteString("	return &cloned\n}\n\n")
+	if reuse {
+		buf.WriteString("// Reset sets all fields of " + shortName + " to zero values in place. memory of maps and arrays is kept for decoding,\n")
+		buf.WriteString("// and nested messages are released into their pools, so they must not be used after " + shortName + " is reset\n")
+		buf.WriteString(funcName + " Reset() {\n")
+		for _, field := range fields {
+			gt.resetValue(buf, field.Type, shortName+"."+firstUpper(field.Name), "	", 1, schema, context)
+		}
+		buf.WriteString("}\n\n")
+		importStr = append(importStr, "sync")
+		pool := firstLower(message.Name) + "Pool"
+		buf.WriteString("var " + pool + " = sync.Pool{New: func() interface{} { return &" + message.Name + "{} }}\n\n")
+		buf.WriteString("// Acquire" + message.Name + " returns an empty " + message.Name + " from pool, it can be put back by Release" + message.Name + " when it is not used any more\n")
+		buf.WriteString("func Acquire" + message.Name + "() *" + message.Name + " {\n	return " + pool + ".Get().(*" + message.Name + ")\n}\n\n")
+		buf.WriteString("// Release" + message.Name + " resets " + shortName + " and puts it back into pool, " + shortName + " and its nested messages must not be used after released\n")
+		buf.WriteString("func Release" + message.Name + "(" + shortName + " *" + message.Name + ") {\n	if " + shortName + " != nil {\n		" + shortName + ".Reset()\n		" + pool + ".Put(" + shortName + ")\n	}\n}\n\n")
+	} else {
+		buf.WriteString("// Reset sets all fields of " + shortName + " to zero values\n")
+		buf.WriteString(funcName + " Reset() {\n	*" + shortName + " = " + message.Name + "{}\n}\n\n")
+	}
+
 	//interface methods
 	gt.addCommonInterfaceMethod(funcName, gt.schemaName(message.Name), buf)
 	return importStr, nil
 }
 
+// reuseMessage returns whether message is decoded in place and pooled, according to option go_reuse
+func reuseMessage(message *core.Message) bool {
+	b, _ := strconv.ParseBool(message.Options[core.GoReuse])
+	return b && !message.IsEnum
+}
+
+// pooledMessage returns the package prefix and name of message type tp if it is pooled, such as `p.` and `M`.
+func (gt *GoTemplate) pooledMessage(tp *core.Type, schema *core.Schema, context *core.Context) (prefix string, name string, ok bool) {
+	fullName := tp.Name
+	if strings.Index(fullName, ".") < 0 {
+		fullName = schema.Package + "." + fullName
+	}
+	if message := context.GetMessage(fullName); message == nil || !reuseMessage(message) {
+		return "", "", false
+	}
+	tpStr, _ := gt.goTypeString(tp, schema, context, nil)
+	tpStr = tpStr[1:]
+	index := strings.LastIndex(tpStr, ".")
+	return tpStr[:index+1], tpStr[index+1:], true
+}
+
+// containsMessage returns whether values of type tp contain messages which are not enums
+func containsMessage(tp *core.Type, schema *core.Schema, context *core.Context) bool {
+	switch tp.Number {
+	case core.Array, core.Map:
+		return containsMessage(tp.ValueType, schema, context)
+	case core.Msg:
+		return !isEnum(tp, schema, context)
+	}
+	return false
+}
+
+// ptr adds parentheses to dereferenced name, so it can be indexed or sliced
+func ptr(name string) string {
+	if strings.HasPrefix(name, "*") {
+		return "(" + name + ")"
+	}
+	return name
+}
+
+// resetValue writes the statements which reset name of type tp in place, maps and arrays are emptied and keep their memory,
+// and nested messages are released into pools
+func (gt *GoTemplate) resetValue(buf *bytes.Buffer, tp *core.Type, name string, blank string, recursion int, schema *core.Schema, context *core.Context) {
+	recStr := strconv.Itoa(recursion)
+	switch tp.Number {
+	case core.Array:
+		if tp.ValueType.Number == core.Bytes || tp.ValueType.Number >= core.Map {
+			buf.WriteString(blank + "for i" + recStr + " := range " + name + " {\n")
+			gt.resetValue(buf, tp.ValueType, ptr(name)+"[i"+recStr+"]", blank+"	", recursion+1, schema, context)
+			buf.WriteString(blank + "}\n")
+		}
+		buf.WriteString(blank + name + " = " + ptr(name) + "[:0]\n")
+	case core.Map:
+		if containsMessage(tp.ValueType, schema, context) {
+			buf.WriteString(blank + "for k" + recStr + ", v" + recStr + " := range " + name + " {\n")
+			gt.releaseValue(buf, tp.ValueType, "v"+recStr, blank+"	", recursion+1, schema, context)
+		} else {
+			buf.WriteString(blank + "for k" + recStr + " := range " + name + " {\n")
+		}
+		buf.WriteString(blank + "	delete(" + name + ", k" + recStr + ")\n" + blank + "}\n")
+	case core.Msg:
+		if prefix, msgName, ok := gt.pooledMessage(tp, schema, context); ok {
+			buf.WriteString(blank + prefix + "Release" + msgName + "(" + name + ")\n")
+		}
+		buf.WriteString(blank + name + " = nil\n")
+	default:
+		buf.WriteString(blank + name + " = " + goZeroValue(tp) + "\n")
+	}
+}
+
+// releaseValue writes the statements which release the messages in name of type tp into pools
+func (gt *GoTemplate) releaseValue(buf *bytes.Buffer, tp *core.Type, name string, blank string, recursion int, schema *core.Schema, context *core.Context) {
+	recStr := strconv.Itoa(recursion)
+	switch tp.Number {
+	case core.Array, core.Map:
+		buf.WriteString(blank + "for _, v" + recStr + " := range " + name + " {\n")
+		gt.releaseValue(buf, tp.ValueType, "v"+recStr, blank+"	", recursion+1, schema, context)
+		buf.WriteString(blank + "}\n")
+	case core.Msg:
+		if prefix, msgName, ok := gt.pooledMessage(tp, schema, context); ok {
+			buf.WriteString(blank + prefix + "Release" + msgName + "(" + name + ")\n")
+		}
+	}
+}
+
+// readInPlace writes the statements which decode a value of type tp into name, reusing the memory of name.
+// the statements set err, and return it if the value can not be decoded. name is newly declared if fresh is true, so it is not reused.
+func (gt *GoTemplate) readInPlace(buf *bytes.Buffer, tp *core.Type, name string, withType bool, fresh bool, blank string, recursion int, schema *core.Schema, context *core.Context) {
+	recStr := strconv.Itoa(recursion)
+	withTypeStr := strconv.FormatBool(withType)
+	switch tp.Number {
+	case core.Array:
+		tpStr, _ := gt.goTypeString(tp, schema, context, nil)
+		buf.WriteString(blank + "var size" + recStr + " int\n")
+		buf.WriteString(blank + "if size" + recStr + ", err = breeze.ReadPackedSize(buf, " + withTypeStr + "); err != nil {\n" + blank + "	return err\n" + blank + "}\n")
+		if fresh {
+			buf.WriteString(blank + name + " = make(" + tpStr + ", 0, size" + recStr + ")\n")
+		} else {
+			gt.resetValue(buf, tp, name, blank, recursion, schema, context)
+			buf.WriteString(blank + "if cap(" + name + ") < size" + recStr + " {\n" + blank + "	" + name + " = make(" + tpStr + ", 0, size" + recStr + ")\n" + blank + "}\n")
+		}
+		buf.WriteString(blank + "err = breeze.ReadPacked(buf, size" + recStr + ", false, func(buf *breeze.Buffer) (err error) {\n")
+		buf.WriteString(blank + "	" + name + " = " + ptr(name) + "[:len(" + name + ")+1]\n")
+		buf.WriteString(blank + "	v" + recStr + " := &" + ptr(name) + "[len(" + name + ")-1]\n")
+		gt.readInPlace(buf, tp.ValueType, "*v"+recStr, false, false, blank+"	", recursion+1, schema, context)
+		buf.WriteString(blank + "	return err\n" + blank + "})\n")
+	case core.Map:
+		tpStr, _ := gt.goTypeString(tp, schema, context, nil)
+		valueTpStr, _ := gt.goTypeString(tp.ValueType, schema, context, nil)
+		buf.WriteString(blank + "var size" + recStr + " int\n")
+		buf.WriteString(blank + "if size" + recStr + ", err = breeze.ReadPackedSize(buf, " + withTypeStr + "); err != nil {\n" + blank + "	return err\n" + blank + "}\n")
+		if fresh {
+			buf.WriteString(blank + name + " = make(" + tpStr + ", size" + recStr + ")\n")
+		} else {
+			buf.WriteString(blank + "if " + name + " == nil {\n" + blank + "	" + name + " = make(" + tpStr + ", size" + recStr + ")\n" + blank + "} else {\n")
+			gt.resetValue(buf, tp, name, blank+"	", recursion, schema, context)
+			buf.WriteString(blank + "}\n")
+		}
+		buf.WriteString(blank + "err = breeze.ReadPacked(buf, size" + recStr + ", true, func(buf *breeze.Buffer) (err error) {\n")
+		buf.WriteString(blank + "	var k" + recStr + " " + goTypes[tp.KeyType.Number].typeString + "\n")
+		buf.WriteString(blank + "	if k" + recStr + ", err = " + readWithoutType(tp.KeyType) + "; err != nil {\n" + blank + "		return err\n" + blank + "	}\n")
+		buf.WriteString(blank + "	var v" + recStr + " " + valueTpStr + "\n")
+		gt.readInPlace(buf, tp.ValueType, "v"+recStr, false, true, blank+"	", recursion+1, schema, context)
+		buf.WriteString(blank + "	if err == nil {\n" + blank + "		" + ptr(name) + "[k" + recStr + "] = v" + recStr + "\n" + blank + "	}\n")
+		buf.WriteString(blank + "	return err\n" + blank + "})\n")
+	case core.Msg:
+		tpStr, _ := gt.goTypeString(tp, schema, context, nil)
+		tpStr = tpStr[1:]
+		if isEnum(tp, schema, context) {
+			buf.WriteString(blank + "var result interface{}\n")
+			if withType {
+				buf.WriteString(blank + "if result, err = breeze.ReadByEnum(buf, " + tpStr + "(0), true); err == nil {\n")
+			} else {
+				buf.WriteString(blank + "if result, err = " + tpStr + "(0).ReadEnum(buf, true); err == nil {\n")
+			}
+			buf.WriteString(blank + "	" + name + " = result.(*" + tpStr + ")\n" + blank + "}\n")
+			return
+		}
+		newStr := "&" + tpStr + "{}"
+		if prefix, msgName, ok := gt.pooledMessage(tp, schema, context); ok {
+			newStr = prefix + "Acquire" + msgName + "()"
+		}
+		if fresh {
+			buf.WriteString(blank + name + " = " + newStr + "\n")
+		} else {
+			buf.WriteString(blank + "if " + name + " == nil {\n" + blank + "	" + name + " = " + newStr + "\n")
+			buf.WriteString(blank + "} else {\n" + blank + "	" + ptr(name) + ".Reset()\n" + blank + "}\n")
+		}
+		if withType {
+			buf.WriteString(blank + "err = breeze.ReadByMessage(buf, " + name + ")\n")
+		} else {
+			buf.WriteString(blank + "err = " + ptr(name) + ".ReadFrom(buf)\n")
+		}
+	default:
+		if withType {
+			buf.WriteString(blank + "err = " + goTypes[tp.Number].readTypeString + "(buf, &" + ptr(name) + ")\n")
+		} else {
+			buf.WriteString(blank + name + ", err = " + readWithoutType(tp) + "\n")
+		}
+	}
+}
+
+// readWithoutType returns the expression which reads a primitive value without type
+func readWithoutType(tp *core.Type) string {
+	if tp.Number == core.Byte {
+		return "buf.ReadByte()"
+	}
+	return goTypes[tp.Number].readTypeString + "WithoutType(buf)"
+}
+
+// equalValue writes the statements which return false if values a and b of type tp are not deeply equal
+func (gt *GoTemplate) equalValue(buf *bytes.Buffer, tp *core.Type, a string, b string, blank string, recursion int, schema *core.Schema, context *core.Context, importStr []string) []string {
+	recStr := strconv.Itoa(recursion)
+	switch tp.Number {
+	case core.Bytes:
+		importStr = append(importStr, "bytes")
+		buf.WriteString(blank + "if !bytes.Equal(" + a + ", " + b + ") {\n")
+	case core.Array:
+		buf.WriteString(blank + "if len(" + a + ") != len(" + b + ") {\n" + blank + "	return false\n" + blank + "}\n")
+		buf.WriteString(blank + "for i" + recStr + ", v" + recStr + " := range " + a + " {\n")
+		importStr = gt.equalValue(buf, tp.ValueType, "v"+recStr, b+"[i"+recStr+"]", blank+"	", recursion+1, schema, context, importStr)
+		buf.WriteString(blank + "}\n")
+		return importStr
+	case core.Map:
+		buf.WriteString(blank + "if len(" + a + ") != len(" + b + ") {\n" + blank + "	return false\n" + blank + "}\n")
+		buf.WriteString(blank + "for k" + recStr + ", v" + recStr + " := range " + a + " {\n")
+		buf.WriteString(blank + "	w" + recStr + ", ok := " + b + "[k" + recStr + "]\n" + blank + "	if !ok {\n" + blank + "		return false\n" + blank + "	}\n")
+		importStr = gt.equalValue(buf, tp.ValueType, "v"+recStr, "w"+recStr, blank+"	", recursion+1, schema, context, importStr)
+		buf.WriteString(blank + "}\n")
+		return importStr
+	case core.Msg:
+		if isEnum(tp, schema, context) {
+			buf.WriteString(blank + "if (" + a + " == nil) != (" + b + " == nil) || (" + a + " != nil && *" + a + " != *" + b + ") {\n")
+		} else {
+			buf.WriteString(blank + "if !" + a + ".Equal(" + b + ") {\n")
+		}
+	default:
+		buf.WriteString(blank + "if " + a + " != " + b + " {\n")
+	}
+	buf.WriteString(blank + "	return false\n" + blank + "}\n")
+	return importStr
+}
+
+// cloneValue writes the statements which set dst to a deep copy of src of type tp
+func (gt *GoTemplate) cloneValue(buf *bytes.Buffer, tp *core.Type, src string, dst string, blank string, recursion int, schema *core.Schema, context *core.Context) {
+	recStr := strconv.Itoa(recursion)
+	switch tp.Number {
+	case core.Bytes:
+		buf.WriteString(blank + "if " + src + " != nil {\n" + blank + "	" + dst + " = append([]byte{}, " + src + "...)\n" + blank + "}\n")
+	case core.Array:
+		tpStr, _ := gt.goTypeString(tp, schema, context, nil)
+		buf.WriteString(blank + "if " + src + " != nil {\n" + blank + "	" + dst + " = make(" + tpStr + ", len(" + src + "))\n")
+		buf.WriteString(blank + "	for i" + recStr + ", v" + recStr + " := range " + src + " {\n")
+		gt.cloneValue(buf, tp.ValueType, "v"+recStr, dst+"[i"+recStr+"]", blank+"		", recursion+1, schema, context)
+		buf.WriteString(blank + "	}\n" + blank + "}\n")
+	case core.Map:
+		tpStr, _ := gt.goTypeString(tp, schema, context, nil)
+		valueTpStr, _ := gt.goTypeString(tp.ValueType, schema, context, nil)
+		buf.WriteString(blank + "if " + src + " != nil {\n" + blank + "	" + dst + " = make(" + tpStr + ", len(" + src + "))\n")
+		buf.WriteString(blank + "	for k" + recStr + ", v" + recStr + " := range " + src + " {\n")
+		if tp.ValueType.Number == core.Bytes || tp.ValueType.Number >= core.Map && (tp.ValueType.Number != core.Msg || isEnum(tp.ValueType, schema, context)) {
+			// the element is set conditionally, so it is copied into a variable first to keep nil elements
+			buf.WriteString(blank + "		var w" + recStr + " " + valueTpStr + "\n")
+			gt.cloneValue(buf, tp.ValueType, "v"+recStr, "w"+recStr, blank+"		", recursion+1, schema, context)
+			buf.WriteString(blank + "		" + dst + "[k" + recStr + "] = w" + recStr + "\n")
+		} else {
+			gt.cloneValue(buf, tp.ValueType, "v"+recStr, dst+"[k"+recStr+"]", blank+"		", recursion+1, schema, context)
+		}
+		buf.WriteString(blank + "	}\n" + blank + "}\n")
+	case core.Msg:
+		if isEnum(tp, schema, context) {
+			buf.WriteString(blank + "if " + src + " != nil {\n" + blank + "	value := *" + src + "\n" + blank + "	" + dst + " = &value\n" + blank + "}\n")
+		} else {
+			buf.WriteString(blank + dst + " = " + src + ".Clone()\n")
+		}
+	default:
+		buf.WriteString(blank + dst + " = " + src + "\n")
+	}
+}
+
 func (gt *GoTemplate) writeMap(buf *bytes.Buffer, tp *core.Type, name string, recursion int) {
 	blank := "			"
 	for i := 0; i < recursion; i++ {
@@ -308,16 +1085,18 @@ func (gt *GoTemplate) writeMap(buf *bytes.Buffer, tp *core.Type, name string, re
 		buf.WriteString(blank + "for k" + recStr + ", v" + recStr + " := range " + name + " {")
 		buf.WriteString(blank + "	" + goTypes[tp.KeyType.Number].writeTypeString + "(buf, k" + recStr + ", false)\n")
 		buf.WriteString(blank + "	breeze.WritePackedMap(buf, false, len(v" + recStr + "), func(buf *breeze.Buffer) {\n")
-		gt.writeMap(buf, tp.ValueType, "v"+recStr, recursion+1)
-		buf.WriteString(blank + "	})\n" + blank + "}\n")
+		buf.WriteString(blank + "		if len(v" + recStr + ") > 0 { // element types are not read for empty map\n")
+		gt.writeMap(buf, tp.ValueType, "v"+recStr, recursion+2)
+		buf.WriteString(blank + "		}\n" + blank + "	})\n" + blank + "}\n")
 	case core.Array:
 		buf.WriteString(blank + goTypes[tp.KeyType.Number].writeTypeString + "Type(buf)\n")
 		buf.WriteString(blank + "breeze.WritePackedArrayType(buf)\n")
 		buf.WriteString(blank + "for k" + recStr + ", v" + recStr + " := range " + name + " {")
 		buf.WriteString(blank + "	" + goTypes[tp.KeyType.Number].writeTypeString + "(buf, k" + recStr + ", false)\n")
 		buf.WriteString(blank + "	breeze.WritePackedArray(buf, false, len(v" + recStr + "), func(buf *breeze.Buffer) {\n")
-		gt.writeArray(buf, tp.ValueType, "v"+recStr, recursion+1)
-		buf.WriteString(blank + "	})\n" + blank + "}\n")
+		buf.WriteString(blank + "		if len(v" + recStr + ") > 0 { // element types are not read for empty array\n")
+		gt.writeArray(buf, tp.ValueType, "v"+recStr, recursion+2)
+		buf.WriteString(blank + "		}\n" + blank + "	})\n" + blank + "}\n")
 	case core.Msg:
 		buf.WriteString(blank + "first := true\n")
 		buf.WriteString(blank + "for k" + recStr + ", v" + recStr + " := range " + name + " {\n")
@@ -360,14 +1139,16 @@ func (gt *GoTemplate) writeArray(buf *bytes.Buffer, tp *core.Type, name string,
 		buf.WriteString(blank + "breeze.WritePackedMapType(buf)\n")
 		buf.WriteString(blank + "for _, v" + recStr + " := range " + name + " {")
 		buf.WriteString(blank + "	breeze.WritePackedMap(buf, false, len(v" + recStr + "), func(buf *breeze.Buffer) {\n")
-		gt.writeMap(buf, tp.ValueType, "v"+recStr, recursion+1)
-		buf.WriteString(blank + "	})\n" + blank + "}\n")
+		buf.WriteString(blank + "		if len(v" + recStr + ") > 0 { // element types are not read for empty map\n")
+		gt.writeMap(buf, tp.ValueType, "v"+recStr, recursion+2)
+		buf.WriteString(blank + "		}\n" + blank + "	})\n" + blank + "}\n")
 	case core.Array:
 		buf.WriteString(blank + "breeze.WritePackedArrayType(buf)\n")
 		buf.WriteString(blank + "for _, v" + recStr + " := range " + name + " {")
 		buf.WriteString(blank + "	breeze.WritePackedArray(buf, false, len(v" + recStr + "), func(buf *breeze.Buffer) {\n")
-		gt.writeArray(buf, tp.ValueType, "v"+recStr, recursion+1)
-		buf.WriteString(blank + "	})\n" + blank + "}\n")
+		buf.WriteString(blank + "		if len(v" + recStr + ") > 0 { // element types are not read for empty array\n")
+		gt.writeArray(buf, tp.ValueType, "v"+recStr, recursion+2)
+		buf.WriteString(blank + "		}\n" + blank + "	})\n" + blank + "}\n")
 	case core.Msg:
 		buf.WriteString(blank + "first := true\n")
 		buf.WriteString(blank + "for _, v" + recStr + " := range " + name + " {\n")
@@ -406,13 +1187,7 @@ func (gt *GoTemplate) readMap(buf *bytes.Buffer, tp *core.Type, name string, rec
 	}
 	recStr := strconv.Itoa(recursion)
 	buf.WriteString(blank + "size, err := breeze.ReadPackedSize(buf, " + withType + ")\n" + blank + "if err != nil {\n" + blank + "	return err\n" + blank + "}\n")
-	tpStr := gt.getTypeString(tp)
-	if strings.Contains(tpStr, ".") {
-		tps := []string{}
-		importStr0 := gt.getTypeImport(schema, tp, tps, context)
-		importStr0, typeString0 := gt.getImportInfoByType(tp, importStr0, context, schema)
-		tpStr = typeString0
-	}
+	tpStr, _ := gt.goTypeString(tp, schema, context, nil)
 	buf.WriteString(blank + name + " " + assign + " make(" + tpStr + ", size)\n")
 	buf.WriteString(blank + "err = breeze.ReadPacked(buf, size, true, func(buf *breeze.Buffer) error {\n")
 	//read key
@@ -475,13 +1250,7 @@ func (gt *GoTemplate) readArray(buf *bytes.Buffer, tp *core.Type, name string, r
 	recStr := strconv.Itoa(recursion)
 	buf.WriteString(blank + "size, err := breeze.ReadPackedSize(buf, " + withType + ")\n" + blank + "if err != nil {\n" + blank + "	return err\n" + blank + "}\n")
 
-	tpStr := gt.getTypeString(tp)
-	if strings.Contains(tpStr, ".") {
-		tps := []string{}
-		importStr0 := gt.getTypeImport(schema, tp, tps, context)
-		importStr0, typeString0 := gt.getImportInfoByType(tp, importStr0, context, schema)
-		tpStr = typeString0
-	}
+	tpStr, _ := gt.goTypeString(tp, schema, context, nil)
 	buf.WriteString(blank + name + " " + assign + " make(" + tpStr + ", 0, size)\n")
 	buf.WriteString(blank + "err = breeze.ReadPacked(buf, size, false, func(buf *breeze.Buffer) error {\n")
 
@@ -514,8 +1283,48 @@ func (gt *GoTemplate) readArray(buf *bytes.Buffer, tp *core.Type, name string, r
 	}
 }
 
+// fieldTag returns the struct tag of field. the json tag is named according to option go_json_tag,
+// and field options with prefix `tag.` are extra tags, they can also override the json tag.
+func (gt *GoTemplate) fieldTag(message *core.Message, field *core.Field) (string, error) {
+	tags := make(map[string]string)
+	switch jsonTag := message.Options[core.GoJSONTag]; jsonTag {
+	case "", "none":
+	case "original":
+		tags["json"] = field.Name
+	case "snake":
+		tags["json"] = snakeCase(field.Name)
+	case "camel":
+		tags["json"] = lowerCamelCase(field.Name)
+	default:
+		return "", fmt.Errorf("wrong %s: %s, message: %s", core.GoJSONTag, jsonTag, message.Name)
+	}
+	for key, value := range field.Options {
+		if strings.HasPrefix(key, core.TagPrefix) {
+			tags[key[len(core.TagPrefix):]] = value
+		}
+	}
+	if len(tags) == 0 {
+		return "", nil
+	}
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		if key != "json" { // json tag is the first
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := tags["json"]; ok {
+		keys = append([]string{"json"}, keys...)
+	}
+	items := make([]string, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, key+":"+strconv.Quote(tags[key]))
+	}
+	return " `" + strings.Join(items, " ") + "`", nil
+}
+
 func (gt *GoTemplate) generateEnum(schema *core.Schema, message *core.Message, context *core.Context, buf *bytes.Buffer, importStr []string) ([]string, error) {
-	importStr = append(importStr, "errors", "strconv")
+	importStr = append(importStr, "encoding/json", "errors", "strconv")
 	// const
 	buf.WriteString("\nconst (\n")
 	fields := sortEnumValues(message) //sorted enum values
@@ -537,63 +1346,529 @@ func (gt *GoTemplate) generateEnum(schema *core.Schema, message *core.Message, c
 	buf.WriteString(funcName + " ReadFrom(buf *breeze.Buffer) error {\n	return errors.New(\"can not read enum by Message.ReadFrom, Enum.ReadEnum is expected. name:\" + " + shortName + ".GetName())\n}\n\n")
 
 	// read enum
-	buf.WriteString(funcName + " ReadEnum(buf *breeze.Buffer, asAddr bool) (breeze.Enum, error) {\n	var number int32\n	e := breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {\n")
+	buf.WriteString(funcName + " ReadEnum(buf *breeze.Buffer, asAddr bool) (breeze.Enum, error) {\n	var number int32\n	err := breeze.ReadMessageField(buf, func(buf *breeze.Buffer, index int) (err error) {\n")
 	buf.WriteString("		switch index {\n		case 1:\n			err = breeze.ReadInt32(buf, &number)\n")
 	buf.WriteString("		default: //skip unknown field\n			_, err = breeze.ReadValue(buf, nil)\n		}\n		return err\n	})\n")
-	buf.WriteString("	if e == nil {\n		var result " + message.Name + "\n		switch number {\n")
+	buf.WriteString("	if err == nil {\n		var result " + message.Name + "\n		switch number {\n")
 	for _, v := range fields {
 		buf.WriteString("		case " + strconv.Itoa(v.Index) + ":\n			result = " + message.Name + firstUpper(v.Name) + "\n")
 	}
-	buf.WriteString("		default:\n			return nil, errors.New(\"unknown enum number \" + strconv.Itoa(int(number)))\n		}\n		if asAddr {\n			return &result, nil\n		}\n		return result, nil\n	}\n	return nil, e\n}\n\n")
+	if keepUnknownEnum(message) {
+		buf.WriteString("		default: // keep unknown number, it is encoded unchanged\n			result = " + message.Name + "(number)\n")
+	} else {
+		buf.WriteString("		default:\n			return nil, errors.New(\"unknown enum number \" + strconv.Itoa(int(number)))\n")
+	}
+	buf.WriteString("		}\n		if asAddr {\n			return &result, nil\n		}\n		return result, nil\n	}\n	return nil, err\n}\n\n")
 
+	if keepUnknownEnum(message) {
+		buf.WriteString("// IsRecognized returns false if " + shortName + " is an unknown number kept when decoding\n")
+		buf.WriteString(funcName + " IsRecognized() bool {\n	switch " + shortName + " {\n	case ")
+		for i, v := range fields {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(message.Name + firstUpper(v.Name))
+		}
+		buf.WriteString(":\n		return true\n	}\n	return false\n}\n\n")
+	}
+	gt.generateEnumNames(message, fields, funcName, shortName, buf)
+	gt.generateEnumJSON(message, funcName, shortName, buf)
 	gt.addCommonInterfaceMethod(funcName, gt.schemaName(message.Name), buf)
 	return importStr, nil
 }
 
-func (gt *GoTemplate) getTypeImport(schema *core.Schema, tp *core.Type, tps []string, context *core.Context) []string {
-	switch tp.Number {
-	case core.Array, core.Map: //only array or map value maybe contains message type
-		tps = gt.getTypeImport(schema, tp.ValueType, tps, context)
-	case core.Msg:
-		index := strings.LastIndex(tp.Name, ".")
-		if index > -1 && tp.Name[:index] != schema.Package { //not same package
-			prefix := ""
-			if context.Options != nil {
-				prefix = context.Options[core.GoPackagePrefix]
+// generateEnumNames writes the ordered values, the lookup tables between names and numbers, String and Parse<Enum> of enum
+func (gt *GoTemplate) generateEnumNames(message *core.Message, fields []*core.Field, funcName string, shortName string, buf *bytes.Buffer) {
+	names := firstLower(message.Name) + "Names"
+	numbers := firstLower(message.Name) + "Numbers"
+	buf.WriteString("// " + message.Name + "Values are all values of " + message.Name + " ordered by number\nvar " + message.Name + "Values = []" + message.Name + "{")
+	for i, v := range fields {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(message.Name + firstUpper(v.Name))
+	}
+	buf.WriteString("}\n\nvar " + names + " = map[" + message.Name + "]string{\n")
+	for _, v := range fields {
+		buf.WriteString("	" + message.Name + firstUpper(v.Name) + ": \"" + v.Name + "\",\n")
+	}
+	buf.WriteString("}\n\nvar " + numbers + " = map[string]" + message.Name + "{\n")
+	for _, v := range fields {
+		buf.WriteString("	\"" + v.Name + "\": " + message.Name + firstUpper(v.Name) + ",\n")
+	}
+	buf.WriteString("}\n\n")
+	buf.WriteString("// String returns the name of " + shortName + " in schema, or the number if it is unknown\n")
+	buf.WriteString(funcName + " String() string {\n	if name, ok := " + names + "[" + shortName + "]; ok {\n		return name\n	}\n	return strconv.Itoa(int(" + shortName + "))\n}\n\n")
+	buf.WriteString("// Parse" + message.Name + " returns the value of " + message.Name + " by its name in schema\n")
+	buf.WriteString("func Parse" + message.Name + "(name string) (" + message.Name + ", error) {\n	if value, ok := " + numbers + "[name]; ok {\n		return value, nil\n	}\n")
+	buf.WriteString("	return 0, errors.New(\"unknown name of enum " + message.Name + ": \" + name)\n}\n\n")
+}
+
+// generateEnumJSON writes MarshalJSON and UnmarshalJSON of enum, which use the names of values
+func (gt *GoTemplate) generateEnumJSON(message *core.Message, funcName string, shortName string, buf *bytes.Buffer) {
+	names := firstLower(message.Name) + "Names"
+	buf.WriteString("// MarshalJSON encodes " + shortName + " as its name, or the number if it is unknown\n")
+	buf.WriteString(funcName + " MarshalJSON() ([]byte, error) {\n	if name, ok := " + names + "[" + shortName + "]; ok {\n		return json.Marshal(name)\n	}\n")
+	buf.WriteString("	return json.Marshal(int32(" + shortName + "))\n}\n\n")
+	buf.WriteString("// UnmarshalJSON decodes " + shortName + " from its name or number\n")
+	buf.WriteString("func (" + shortName + " *" + message.Name + ") UnmarshalJSON(data []byte) error {\n	var name string\n")
+	buf.WriteString("	if json.Unmarshal(data, &name) == nil {\n		value, err := Parse" + message.Name + "(name)\n		if err != nil {\n			return err\n		}\n")
+	buf.WriteString("		*" + shortName + " = value\n		return nil\n	}\n")
+	buf.WriteString("	var number int32\n	if err := json.Unmarshal(data, &number); err != nil {\n		return err\n	}\n")
+	if !keepUnknownEnum(message) {
+		buf.WriteString("	if _, ok := " + names + "[" + message.Name + "(number)]; !ok {\n")
+		buf.WriteString("		return errors.New(\"unknown enum number \" + strconv.Itoa(int(number)))\n	}\n")
+	}
+	buf.WriteString("	*" + shortName + " = " + message.Name + "(number)\n	return nil\n}\n\n")
+}
+
+func (gt *GoTemplate) generateService(schema *core.Schema, service *core.Service, context *core.Context, buf *bytes.Buffer, importStr []string) ([]string, error) {
+	importStr = append(importStr, "context")
+	methods := sortMethods(service)
+	buf.WriteString("// " + service.Name + " is the interface of service " + schema.OrgPackage + "." + service.Name + "\n")
+	buf.WriteString("type " + service.Name + " interface {\n")
+	for _, method := range methods {
+		var signature string
+		signature, importStr = gt.methodSignature(schema, method, context, importStr)
+		buf.WriteString("	" + firstUpper(method.Name) + signature + "\n")
+	}
+	buf.WriteString("}\n\n")
+	importStr, err := gt.generateMotanClient(schema, service, context, buf, importStr)
+	if err != nil {
+		return nil, err
+	}
+	importStr, err = gt.generateMotanProvider(schema, service, context, buf, importStr)
+	if b, _ := strconv.ParseBool(schema.Options[core.GoMotan]); err != nil || !b {
+		return importStr, err
+	}
+	importStr = gt.generateMotanGoClient(schema, service, context, buf, importStr)
+	return gt.generateMotanGoProvider(schema, service, context, buf, importStr), nil
+}
+
+// generateMotanClient writes the client of service, which encodes arguments and decodes the return value by breeze.
+// the encoded request is sent by a caller, so the generated code does not depend on motan-go.
+func (gt *GoTemplate) generateMotanClient(schema *core.Schema, service *core.Service, context *core.Context, buf *bytes.Buffer, importStr []string) ([]string, error) {
+	importStr = append(importStr, "github.com/weibreeze/breeze-go")
+	clientName := service.Name + "Client"
+	callerName := service.Name + "Caller"
+	serviceName := schema.Package + "." + service.Name
+	buf.WriteString("// " + callerName + " sends breeze encoded arguments of method to service and returns the breeze encoded reply, such as an adapter of motan-go client\n")
+	buf.WriteString("type " + callerName + " interface {\n	Call(ctx context.Context, service string, method string, args []byte) ([]byte, error)\n}\n\n")
+	buf.WriteString("// " + clientName + " is the motan client of " + service.Name + "\n")
+	buf.WriteString("type " + clientName + " struct {\n	caller " + callerName + "\n}\n\n")
+	buf.WriteString("var _ " + service.Name + " = (*" + clientName + ")(nil)\n\n")
+	buf.WriteString("func New" + clientName + "(caller " + callerName + ") *" + clientName + " {\n	return &" + clientName + "{caller: caller}\n}\n\n")
+	buf.WriteString("func (c *" + clientName + ") call(ctx context.Context, method string, args []byte) ([]byte, error) {\n")
+	buf.WriteString("	if ctx.Done() == nil {\n		return c.caller.Call(ctx, \"" + serviceName + "\", method, args)\n	}\n")
+	buf.WriteString("	if err := ctx.Err(); err != nil {\n		return nil, err\n	}\n")
+	buf.WriteString("	type result struct {\n		reply []byte\n		err   error\n	}\n")
+	buf.WriteString("	done := make(chan result, 1)\n	go func() {\n		reply, err := c.caller.Call(ctx, \"" + serviceName + "\", method, args)\n		done <- result{reply, err}\n	}()\n")
+	buf.WriteString("	select {\n	case r := <-done:\n		return r.reply, r.err\n	case <-ctx.Done():\n		return nil, ctx.Err()\n	}\n}\n\n")
+
+	for _, method := range sortMethods(service) {
+		methodName := firstUpper(method.Name)
+		params := sortParams(method)
+		var signature, returnType string
+		signature, importStr = gt.methodSignature(schema, method, context, importStr)
+		args := make([]string, 0, len(params))
+		buf.WriteString("func (c *" + clientName + ") " + methodName + signature + " {\n	buf := breeze.NewBuffer(64)\n")
+		for _, param := range params {
+			name := goParamName(param.Name)
+			args = append(args, name)
+			gt.writeValue(buf, param.Type, name, "	")
+		}
+		if method.Return == nil {
+			buf.WriteString("	_, err := c.call(ctx, \"" + method.Name + "\", buf.Bytes())\n	return err\n}\n\n")
+		} else {
+			returnType, importStr = gt.goTypeString(method.Return, schema, context, importStr)
+			buf.WriteString("	result, err := c.call(ctx, \"" + method.Name + "\", buf.Bytes())\n")
+			buf.WriteString("	if err != nil {\n		return " + goZeroValue(method.Return) + ", err\n	}\n	buf = breeze.CreateBuffer(result)\n")
+			importStr = gt.readValue(buf, method.Return, "reply", "	", schema, context, importStr)
+			buf.WriteString("	if err != nil {\n		return " + goZeroValue(method.Return) + ", err\n	}\n	return reply, nil\n}\n\n")
+		}
+
+		// async method and its future, like ResponseFuture of motan java
+		futureName := service.Name + methodName + "Future"
+		buf.WriteString("// " + futureName + " is the result of " + clientName + "." + methodName + "Async\n")
+		buf.WriteString("type " + futureName + " struct {\n	done  chan struct{}\n")
+		if method.Return != nil {
+			buf.WriteString("	reply " + returnType + "\n")
+		}
+		buf.WriteString("	err   error\n}\n\n")
+		buf.WriteString("// Done returns a channel which is closed when the call is finished\n")
+		buf.WriteString("func (f *" + futureName + ") Done() <-chan struct{} {\n	return f.done\n}\n\n")
+		buf.WriteString("// Get waits until the call is finished and returns its result\n")
+		if method.Return != nil {
+			buf.WriteString("func (f *" + futureName + ") Get() (" + returnType + ", error) {\n	<-f.done\n	return f.reply, f.err\n}\n\n")
+		} else {
+			buf.WriteString("func (f *" + futureName + ") Get() error {\n	<-f.done\n	return f.err\n}\n\n")
+		}
+		results, zero := "error", "context.DeadlineExceeded"
+		buf.WriteString("// OnDone calls callback with the result in a new goroutine when the call is finished\n")
+		if method.Return != nil {
+			results, zero = "("+returnType+", error)", goZeroValue(method.Return)+", context.DeadlineExceeded"
+			buf.WriteString("func (f *" + futureName + ") OnDone(callback func(" + returnType + ", error)) {\n")
+			buf.WriteString("	go func() {\n		<-f.done\n		callback(f.reply, f.err)\n	}()\n}\n\n")
+		} else {
+			buf.WriteString("func (f *" + futureName + ") OnDone(callback func(error)) {\n")
+			buf.WriteString("	go func() {\n		<-f.done\n		callback(f.err)\n	}()\n}\n\n")
+		}
+		buf.WriteString("// GetWithTimeout waits at most timeout for the call, context.DeadlineExceeded is returned if the call is not finished in time\n")
+		buf.WriteString("func (f *" + futureName + ") GetWithTimeout(timeout time.Duration) " + results + " {\n")
+		buf.WriteString("	timer := time.NewTimer(timeout)\n	defer timer.Stop()\n	select {\n	case <-f.done:\n")
+		if method.Return != nil {
+			buf.WriteString("		return f.reply, f.err\n")
+		} else {
+			buf.WriteString("		return f.err\n")
+		}
+		buf.WriteString("	case <-timer.C:\n		return " + zero + "\n	}\n}\n\n")
+		gt.writeAsync(buf, clientName, methodName, signature, futureName, method.Return != nil, args)
+	}
+	return append(importStr, "time"), nil
+}
+
+// writeAsync writes the async method of client, which calls the method in a new goroutine and returns its future
+func (gt *GoTemplate) writeAsync(buf *bytes.Buffer, clientName string, methodName string, signature string, futureName string, withReturn bool, args []string) {
+	buf.WriteString("func (c *" + clientName + ") " + methodName + "Async" + signature[:strings.Index(signature, ")")+1] + " *" + futureName + " {\n")
+	buf.WriteString("	f := &" + futureName + "{done: make(chan struct{})}\n	go func() {\n		defer close(f.done)\n")
+	call := "c." + methodName + "(" + strings.Join(append([]string{"ctx"}, args...), ", ") + ")"
+	if withReturn {
+		buf.WriteString("		f.reply, f.err = " + call + "\n")
+	} else {
+		buf.WriteString("		f.err = " + call + "\n")
+	}
+	buf.WriteString("	}()\n	return f\n}\n\n")
+}
+
+// generateMotanProvider writes the provider of service, which decodes breeze arguments, dispatches them to the implementation
+// and encodes the return value. the provider is registered by its Invoke method, so the generated code does not depend on motan-go.
+func (gt *GoTemplate) generateMotanProvider(schema *core.Schema, service *core.Service, context *core.Context, buf *bytes.Buffer, importStr []string) ([]string, error) {
+	importStr = append(importStr, "errors", "fmt", "github.com/weibreeze/breeze-go")
+	providerName := service.Name + "Provider"
+	registrarName := service.Name + "Registrar"
+	serviceName := schema.Package + "." + service.Name
+	buf.WriteString("// " + registrarName + " registers service providers to a server, such as an adapter of motan-go server\n")
+	buf.WriteString("type " + registrarName + " interface {\n")
+	buf.WriteString("	Register(service string, invoke func(ctx context.Context, method string, args []byte) ([]byte, error)) error\n}\n\n")
+	buf.WriteString("// Register" + providerName + " registers the implementation of " + service.Name + " to registrar\n")
+	buf.WriteString("func Register" + providerName + "(registrar " + registrarName + ", impl " + service.Name + ") error {\n")
+	buf.WriteString("	provider := New" + providerName + "(impl)\n	return registrar.Register(provider.ServiceName(), provider.Invoke)\n}\n\n")
+	buf.WriteString("// " + providerName + " calls the implementation of " + service.Name + " with breeze encoded arguments\n")
+	buf.WriteString("type " + providerName + " struct {\n	impl " + service.Name + "\n}\n\n")
+	buf.WriteString("func New" + providerName + "(impl " + service.Name + ") *" + providerName + " {\n	return &" + providerName + "{impl: impl}\n}\n\n")
+	buf.WriteString("// ServiceName returns the service name used by motan\n")
+	buf.WriteString("func (p *" + providerName + ") ServiceName() string {\n	return \"" + serviceName + "\"\n}\n\n")
+
+	buf.WriteString("// Invoke decodes the breeze encoded arguments of method, calls the implementation and returns the breeze encoded return value\n")
+	buf.WriteString("func (p *" + providerName + ") Invoke(ctx context.Context, method string, args []byte) (result []byte, err error) {\n")
+	buf.WriteString("	defer func() {\n		if r := recover(); r != nil {\n")
+	buf.WriteString("			err = fmt.Errorf(\"call method %s of service " + serviceName + " panic: %v\", method, r)\n		}\n	}()\n")
+	methods := sortMethods(service)
+	withArgs := false
+	for _, method := range methods {
+		withArgs = withArgs || len(method.Params) > 0
+	}
+	if withArgs {
+		buf.WriteString("	buf := breeze.CreateBuffer(args)\n")
+	}
+	buf.WriteString("	switch method {\n")
+	for _, method := range methods {
+		buf.WriteString("	case \"" + method.Name + "\":\n")
+		args := []string{"ctx"}
+		for _, param := range sortParams(method) {
+			name := goParamName(param.Name)
+			importStr = gt.readValue(buf, param.Type, name, "		", schema, context, importStr)
+			buf.WriteString("		if err != nil {\n")
+			buf.WriteString("			return nil, fmt.Errorf(\"decode argument " + param.Name + " of method " + method.Name + " fail: %v\", err)\n		}\n")
+			args = append(args, name)
+		}
+		call := "p.impl." + firstUpper(method.Name) + "(" + strings.Join(args, ", ") + ")"
+		if method.Return == nil {
+			buf.WriteString("		if err = " + call + "; err != nil {\n			return nil, err\n		}\n		return nil, nil\n")
+		} else {
+			buf.WriteString("		reply, err := " + call + "\n		if err != nil {\n			return nil, err\n		}\n")
+			if withArgs {
+				buf.WriteString("		buf = breeze.NewBuffer(64)\n")
+			} else {
+				buf.WriteString("		buf := breeze.NewBuffer(64)\n")
 			}
-			tps = append(tps, prefix+strings.ReplaceAll(tp.Name[:index], ".", "/"))
+			gt.writeValue(buf, method.Return, "reply", "		")
+			buf.WriteString("		return buf.Bytes(), nil\n")
 		}
 	}
-	return tps
+	buf.WriteString("	default:\n		return nil, errors.New(\"unknown method \" + method + \" of service " + serviceName + "\")\n	}\n}\n\n")
+	return importStr, nil
 }
 
-func (gt *GoTemplate) getTypeString(tp *core.Type) string {
-	if tp.Number < core.Map {
-		return goTypes[tp.Number].typeString
+// generateMotanGoClient writes the adapter of motan-go client, which implements the service by MCClient of motan-go.
+// arguments and return values are encoded by the breeze serialization of motan-go with the generated types.
+func (gt *GoTemplate) generateMotanGoClient(schema *core.Schema, service *core.Service, context *core.Context, buf *bytes.Buffer, importStr []string) []string {
+	importStr = append(importStr, "motan github.com/weibocom/motan-go", "motancore github.com/weibocom/motan-go/core")
+	clientName := service.Name + "MotanClient"
+	buf.WriteString("// " + clientName + " is the " + service.Name + " client over the MCClient of motan-go, the serialization of client must be breeze\n")
+	buf.WriteString("type " + clientName + " struct {\n	client *motan.MCClient\n}\n\n")
+	buf.WriteString("var _ " + service.Name + " = (*" + clientName + ")(nil)\n\n")
+	buf.WriteString("func New" + clientName + "(client *motan.MCClient) *" + clientName + " {\n	return &" + clientName + "{client: client}\n}\n\n")
+	buf.WriteString("// call returns when the response is received, or ctx is canceled or timed out\n")
+	buf.WriteString("func (c *" + clientName + ") call(ctx context.Context, method string, args []interface{}, reply interface{}) error {\n")
+	buf.WriteString("	if err := ctx.Err(); err != nil {\n		return err\n	}\n")
+	buf.WriteString("	result := c.client.Go(method, args, reply, make(chan *motancore.AsyncResult, 1))\n")
+	buf.WriteString("	select {\n	case <-result.Done:\n		return result.Error\n	case <-ctx.Done():\n		return ctx.Err()\n	}\n}\n\n")
+
+	for _, method := range sortMethods(service) {
+		methodName := firstUpper(method.Name)
+		params := sortParams(method)
+		var signature, typeString string
+		signature, importStr = gt.methodSignature(schema, method, context, importStr)
+		names, args := make([]string, 0, len(params)), make([]string, 0, len(params))
+		for _, param := range params {
+			name := goParamName(param.Name)
+			names = append(names, name)
+			if param.Type.Number == core.Msg { // nil message can not be written by breeze.WriteValue, it is encoded as null
+				name = "nil"
+			}
+			args = append(args, name)
+		}
+		buf.WriteString("func (c *" + clientName + ") " + methodName + signature + " {\n")
+		buf.WriteString("	args := []interface{}{" + strings.Join(args, ", ") + "}\n")
+		for i, param := range params {
+			if param.Type.Number == core.Msg {
+				buf.WriteString("	if " + names[i] + " != nil {\n		args[" + strconv.Itoa(i) + "] = " + names[i] + "\n	}\n")
+			}
+		}
+		if method.Return == nil {
+			buf.WriteString("	return c.call(ctx, \"" + method.Name + "\", args, nil)\n}\n\n")
+		} else {
+			typeString, importStr = gt.goTypeString(method.Return, schema, context, importStr)
+			reply := "&reply"
+			if method.Return.Number == core.Map { // breeze.ReadValue puts entries into the map
+				buf.WriteString("	reply := " + typeString + "{}\n")
+			} else if method.Return.Number != core.Msg {
+				buf.WriteString("	var reply " + typeString + "\n")
+			} else if reply = "reply"; isEnum(method.Return, schema, context) {
+				buf.WriteString("	reply := new(" + typeString[1:] + ")\n")
+			} else {
+				buf.WriteString("	reply := &" + typeString[1:] + "{}\n")
+			}
+			buf.WriteString("	if err := c.call(ctx, \"" + method.Name + "\", args, " + reply + "); err != nil {\n")
+			buf.WriteString("		return " + goZeroValue(method.Return) + ", err\n	}\n	return reply, nil\n}\n\n")
+		}
+		gt.writeAsync(buf, clientName, methodName, signature, service.Name+methodName+"Future", method.Return != nil, names)
 	}
+	return importStr
+}
+
+// generateMotanGoProvider writes the adapter of motan-go provider, which decodes arguments with the generated types
+// by the breeze serialization of motan-go and dispatches them to the implementation of service.
+func (gt *GoTemplate) generateMotanGoProvider(schema *core.Schema, service *core.Service, context *core.Context, buf *bytes.Buffer, importStr []string) []string {
+	importStr = append(importStr, "fmt", "strconv", "time", "motancore github.com/weibocom/motan-go/core")
+	providerName := service.Name + "MotanProvider"
+	requestKey := firstLower(service.Name) + "MotanRequestKey"
+	serviceName := schema.Package + "." + service.Name
+	buf.WriteString("// " + providerName + "Name is the name of " + providerName + " in the extension factory of motan-go\n")
+	buf.WriteString("const " + providerName + "Name = \"breeze." + serviceName + "\"\n\n")
+	buf.WriteString("// Register" + providerName + " registers " + providerName + " to the extension factory of motan-go, such as motan.GetDefaultExtFactory().\n")
+	buf.WriteString("// services configured with `provider: " + "breeze." + serviceName + "` use it, and the implementation of " + service.Name + " is set by RegisterService of motan-go.\n")
+	buf.WriteString("func Register" + providerName + "(factory motancore.ExtensionFactory) {\n")
+	buf.WriteString("	factory.RegistExtProvider(" + providerName + "Name, func(url *motancore.URL) motancore.Provider {\n")
+	buf.WriteString("		return &" + providerName + "{url: url}\n	})\n}\n\n")
+	buf.WriteString("// " + providerName + " is the motan-go provider of " + service.Name + ", the serialization of service must be breeze\n")
+	buf.WriteString("type " + providerName + " struct {\n	url  *motancore.URL\n	impl " + service.Name + "\n}\n\n")
+	buf.WriteString("var _ motancore.Provider = (*" + providerName + ")(nil)\n\n")
+	buf.WriteString("func New" + providerName + "(url *motancore.URL, impl " + service.Name + ") *" + providerName + " {\n")
+	buf.WriteString("	return &" + providerName + "{url: url, impl: impl}\n}\n\n")
+	buf.WriteString("// SetService sets the implementation, the provider is unavailable if service does not implement " + service.Name + "\n")
+	buf.WriteString("func (p *" + providerName + ") SetService(service interface{}) {\n	p.impl, _ = service.(" + service.Name + ")\n}\n\n")
+	buf.WriteString("func (p *" + providerName + ") GetURL() *motancore.URL {\n	return p.url\n}\n\n")
+	buf.WriteString("func (p *" + providerName + ") SetURL(url *motancore.URL) {\n	p.url = url\n}\n\n")
+	buf.WriteString("func (p *" + providerName + ") IsAvailable() bool {\n	return p.impl != nil\n}\n\n")
+	buf.WriteString("func (p *" + providerName + ") Destroy() {}\n\n")
+	buf.WriteString("func (p *" + providerName + ") GetPath() string {\n	if p.url == nil {\n		return \"" + serviceName + "\"\n	}\n	return p.url.Path\n}\n\n")
+
+	buf.WriteString("// " + service.Name + "MotanRequest returns the motan-go request of ctx passed to the implementation of " + service.Name + " by " + providerName + ",\n")
+	buf.WriteString("// such as to read attachments of the request\n")
+	buf.WriteString("func " + service.Name + "MotanRequest(ctx context.Context) (motancore.Request, bool) {\n")
+	buf.WriteString("	request, ok := ctx.Value(" + requestKey + "{}).(motancore.Request)\n	return request, ok\n}\n\n")
+	buf.WriteString("type " + requestKey + " struct{}\n\n")
+	buf.WriteString("// context returns the context of request passed to the implementation, which carries the request,\n")
+	buf.WriteString("// and is canceled when the call returns or after `requestTimeout` milliseconds of url if it is set\n")
+	buf.WriteString("func (p *" + providerName + ") context(request motancore.Request) (context.Context, context.CancelFunc) {\n")
+	buf.WriteString("	ctx := context.WithValue(context.Background(), " + requestKey + "{}, request)\n")
+	buf.WriteString("	if p.url != nil {\n")
+	buf.WriteString("		if timeout, err := strconv.ParseInt(p.url.Parameters[\"requestTimeout\"], 10, 64); err == nil && timeout > 0 {\n")
+	buf.WriteString("			return context.WithTimeout(ctx, time.Duration(timeout)*time.Millisecond)\n		}\n	}\n")
+	buf.WriteString("	return context.WithCancel(ctx)\n}\n\n")
+
+	buf.WriteString("// Call decodes the arguments of request, calls the implementation and returns its return value or error as the response\n")
+	buf.WriteString("func (p *" + providerName + ") Call(request motancore.Request) (response motancore.Response) {\n")
+	buf.WriteString("	defer func() {\n		if r := recover(); r != nil {\n")
+	buf.WriteString("			response = p.exception(request, motancore.ServiceException, fmt.Sprintf(\"call method %s of service " + serviceName + " panic: %v\", request.GetMethod(), r))\n		}\n	}()\n")
+	buf.WriteString("	if p.impl == nil {\n		return p.exception(request, motancore.ServiceException, \"implementation of service " + serviceName + " is not set\")\n	}\n")
+	buf.WriteString("	ctx, cancel := p.context(request)\n	defer cancel()\n	switch request.GetMethod() {\n")
+	for _, method := range sortMethods(service) {
+		buf.WriteString("	case \"" + method.Name + "\":\n")
+		params := sortParams(method)
+		names, targets := []string{"ctx"}, make([]string, 0, len(params))
+		var typeString string
+		for _, param := range params {
+			name := goParamName(param.Name)
+			names = append(names, name)
+			typeString, importStr = gt.goTypeString(param.Type, schema, context, importStr)
+			if param.Type.Number == core.Map { // breeze.ReadValue puts entries into the map
+				buf.WriteString("		" + name + " := " + typeString + "{}\n")
+				targets = append(targets, "&"+name)
+			} else if param.Type.Number != core.Msg {
+				buf.WriteString("		var " + name + " " + typeString + "\n")
+				targets = append(targets, "&"+name)
+			} else if isEnum(param.Type, schema, context) {
+				buf.WriteString("		" + name + " := new(" + typeString[1:] + ")\n")
+				targets = append(targets, name)
+			} else {
+				buf.WriteString("		" + name + " := &" + typeString[1:] + "{}\n")
+				targets = append(targets, name)
+			}
+		}
+		if len(params) > 0 {
+			buf.WriteString("		if err := request.ProcessDeserializable([]interface{}{" + strings.Join(targets, ", ") + "}); err != nil {\n")
+			buf.WriteString("			return p.exception(request, motancore.ServiceException, \"decode arguments of method " + method.Name + " fail: \"+err.Error())\n		}\n")
+			buf.WriteString("		args := request.GetArguments()\n		if len(args) != " + strconv.Itoa(len(params)) + " {\n")
+			buf.WriteString("			return p.exception(request, motancore.ServiceException, \"method " + method.Name + " needs " + strconv.Itoa(len(params)) + " arguments\")\n		}\n")
+			for i, param := range params {
+				if param.Type.Number == core.Msg { // null is decoded as nil
+					buf.WriteString("		if args[" + strconv.Itoa(i) + "] == nil {\n			" + names[i+1] + " = nil\n		}\n")
+				}
+			}
+		}
+		call := "p.impl." + firstUpper(method.Name) + "(" + strings.Join(names, ", ") + ")"
+		if method.Return == nil {
+			buf.WriteString("		if err := " + call + "; err != nil {\n")
+			buf.WriteString("			return p.exception(request, motancore.BizException, err.Error())\n		}\n		return p.response(request, nil)\n")
+			continue
+		}
+		buf.WriteString("		reply, err := " + call + "\n		if err != nil {\n")
+		buf.WriteString("			return p.exception(request, motancore.BizException, err.Error())\n		}\n")
+		if method.Return.Number == core.Msg { // nil message can not be written by breeze.WriteValue, it is encoded as null
+			buf.WriteString("		if reply == nil {\n			return p.response(request, nil)\n		}\n")
+		}
+		buf.WriteString("		return p.response(request, reply)\n")
+	}
+	buf.WriteString("	default:\n		return p.exception(request, motancore.ServiceException, \"unknown method \"+request.GetMethod()+\" of service " + serviceName + "\")\n	}\n}\n\n")
+	buf.WriteString("func (p *" + providerName + ") response(request motancore.Request, value interface{}) motancore.Response {\n")
+	buf.WriteString("	return &motancore.MotanResponse{RequestID: request.GetRequestID(), Value: value}\n}\n\n")
+	buf.WriteString("func (p *" + providerName + ") exception(request motancore.Request, errType int, message string) motancore.Response {\n")
+	buf.WriteString("	return motancore.BuildExceptionResponse(request.GetRequestID(), &motancore.Exception{ErrCode: 500, ErrMsg: message, ErrType: errType})\n}\n\n")
+	return importStr
+}
+
+// writeValue writes the code encoding value name of type tp with its breeze type into buf
+func (gt *GoTemplate) writeValue(buf *bytes.Buffer, tp *core.Type, name string, blank string) {
 	switch tp.Number {
-	case core.Array:
-		return goTypes[tp.Number].typeString + gt.getTypeString(tp.ValueType)
-	case core.Map:
-		return goTypes[tp.Number].typeString + gt.getTypeString(tp.KeyType) + "]" + gt.getTypeString(tp.ValueType)
+	case core.Map, core.Array:
+		writePacked, writeElems := "breeze.WritePackedMap", gt.writeMap
+		if tp.Number == core.Array {
+			writePacked, writeElems = "breeze.WritePackedArray", gt.writeArray
+		}
+		// element types are not written for empty map and array, the same as fields
+		buf.WriteString(blank + writePacked + "(buf, true, len(" + name + "), func(buf *breeze.Buffer) {\n")
+		buf.WriteString(blank + "	if len(" + name + ") > 0 {\n")
+		writeElems(buf, tp, name, 1)
+		buf.WriteString(blank + "	}\n" + blank + "})\n")
+	case core.Msg: // nil message is encoded as null
+		buf.WriteString(blank + "if " + name + " != nil {\n" + blank + "	breeze.WriteValue(buf, " + name + ")\n")
+		buf.WriteString(blank + "} else {\n" + blank + "	breeze.WriteValue(buf, nil)\n" + blank + "}\n")
+	default:
+		buf.WriteString(blank + goTypes[tp.Number].writeTypeString + "(buf, " + name + ", true)\n")
+	}
+}
+
+// readValue writes the code declaring variable name of type tp and decoding it from buf, the decode error is assigned to err
+func (gt *GoTemplate) readValue(buf *bytes.Buffer, tp *core.Type, name string, blank string, schema *core.Schema, context *core.Context, importStr []string) []string {
+	var typeString string
+	typeString, importStr = gt.goTypeString(tp, schema, context, importStr)
+	switch tp.Number {
+	case core.Map, core.Array:
+		buf.WriteString(blank + "var " + name + " " + typeString + "\n" + blank + "err = func() error {\n")
+		elems := &bytes.Buffer{}
+		if tp.Number == core.Map {
+			gt.readMap(elems, tp, name, 1, schema, context)
+		} else {
+			gt.readArray(elems, tp, name, 1, schema, context)
+		}
+		buf.Write(elems.Bytes())
+		if !strings.HasSuffix(elems.String(), "return err\n") { // direct map and array
+			buf.WriteString(blank + "	return err\n")
+		}
+		buf.WriteString(blank + "}()\n")
 	case core.Msg:
-		index := strings.LastIndex(tp.Name, ".")
-		if index > -1 { //not same package
-			return "*" + tp.Name[strings.LastIndex(tp.Name[:index], ".")+1:]
+		if isEnum(tp, schema, context) {
+			buf.WriteString(blank + name + " := new(" + typeString[1:] + ")\n")
+		} else {
+			buf.WriteString(blank + name + " := &" + typeString[1:] + "{}\n")
 		}
-		return "*" + tp.Name
+		// null is decoded as nil
+		buf.WriteString(blank + "if v, e := breeze.ReadValue(buf, " + name + "); e != nil || v == nil {\n")
+		buf.WriteString(blank + "	" + name + ", err = nil, e\n" + blank + "}\n")
+	default:
+		buf.WriteString(blank + "var " + name + " " + typeString + "\n" + blank + "err = " + goTypes[tp.Number].readTypeString + "(buf, &" + name + ")\n")
 	}
-	return ""
+	return importStr
 }
 
-func (gt *GoTemplate) generateService(schema *core.Schema, service *core.Service, context *core.Context, buf *bytes.Buffer, importStr []string) ([]string, error) {
-	//TODO implement
-	return importStr, nil
+// methodSignature returns the go signature of method without its name, such as `(ctx context.Context, name string) (*User, error)`
+func (gt *GoTemplate) methodSignature(schema *core.Schema, method *core.Method, context *core.Context, importStr []string) (string, []string) {
+	var typeString string
+	params := []string{"ctx context.Context"}
+	for _, param := range sortParams(method) {
+		typeString, importStr = gt.goTypeString(param.Type, schema, context, importStr)
+		params = append(params, goParamName(param.Name)+" "+typeString)
+	}
+	signature := "(" + strings.Join(params, ", ") + ")"
+	if method.Return == nil {
+		return signature + " error", importStr
+	}
+	typeString, importStr = gt.goTypeString(method.Return, schema, context, importStr)
+	return signature + " (" + typeString + ", error)", importStr
 }
 
-func (gt *GoTemplate) generateMotanClient(schema *core.Schema, service *core.Service, context *core.Context, buf *bytes.Buffer) (importStr []string, err error) {
-	//TODO implement
-	return nil, nil
+// goParamName renames params which are go keywords or conflict with identifiers used in generated methods
+func goParamName(name string) string {
+	switch name {
+	case "break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for", "func", "go", "goto",
+		"if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch", "type", "var",
+		"c", "ctx", "f", "err", "reply", "make", "new", "nil", "close", "p", "r", "buf", "out", "args", "method", "result",
+		"v", "e", "breeze", "context", "errors", "fmt", "motan", "motancore", "request", "response":
+		return name + "_"
+	}
+	return name
+}
+
+// goZeroValue returns the zero value literal of tp
+func goZeroValue(tp *core.Type) string {
+	switch tp.Number {
+	case core.Bool:
+		return "false"
+	case core.String:
+		return "\"\""
+	case core.Byte, core.Int16, core.Int32, core.Int64, core.Float32, core.Float64:
+		return "0"
+	}
+	return "nil"
+}
+
+// writeDescriptor writes the binary descriptor set of schema as a byte slice
+func (gt *GoTemplate) writeDescriptor(schema *core.Schema, desc []byte, buf *bytes.Buffer) {
+	name := descriptorName(schema)
+	buf.WriteString("\n// " + name + " is the binary descriptor set of " + schema.Name + ", it can be decoded by descriptor.UnmarshalBinary of breeze-generator\n")
+	buf.WriteString("var " + name + " = []byte{")
+	for i, b := range desc {
+		if i%16 == 0 {
+			buf.WriteString("\n	")
+		} else {
+			buf.WriteString(" ")
+		}
+		buf.WriteString(fmt.Sprintf("0x%02x,", b))
+	}
+	buf.WriteString("\n}\n")
 }
 
 func (gt *GoTemplate) schemaName(name string) string {
@@ -610,7 +1885,8 @@ func (gt *GoTemplate) writeGoImport(importStrs []string, buf *bytes.Buffer) {
 	sys := make([]string, 0, 16)
 	out := make([]string, 0, 16)
 	for _, value := range importStrs {
-		if strings.Contains(value, "/") {
+		// standard library such as encoding/json has no domain, and only generated packages have alias
+		if strings.Contains(value, " ") || strings.Contains(strings.SplitN(value, "/", 2)[0], ".") {
 			out = append(out, value)
 		} else {
 			sys = append(sys, value)
@@ -620,7 +1896,9 @@ func (gt *GoTemplate) writeGoImport(importStrs []string, buf *bytes.Buffer) {
 	for _, value := range sortUnique(sys) {
 		buf.WriteString("	\"" + value + "\"\n")
 	}
-	buf.WriteString("\n")
+	if len(sys) > 0 && len(out) > 0 {
+		buf.WriteString("\n")
+	}
 	for _, value := range sortUnique(out) {
 		if strings.Contains(value, " ") {
 			buf.WriteString("	" + strings.Replace(value, " ", " \"", 1) + "\"\n")
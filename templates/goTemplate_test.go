@@ -0,0 +1,749 @@
+package templates_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	generator "github.com/weibreeze/breeze-generator"
+	"github.com/weibreeze/breeze-generator/core"
+	"github.com/weibreeze/breeze-generator/descriptor"
+)
+
+func TestGoPackage(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{
+		"a.breeze": "package test.a;\nmessage A {\n    test.b.B b = 1;\n    test.c.C c = 2;\n}\n",
+		"b.breeze": "package test.b;\noption go_package = github.com/demo/bpb bpb;\nmessage B {\n    int32 id = 1;\n}\n",
+		"c.breeze": "package test.c;\noption go_package = github.com/demo/c-go;\nmessage C {\n    int32 id = 1;\n}\n",
+	}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go"})
+	assert.Nil(err)
+	assert.Contains(code["b.go"], "\npackage bpb\n")
+	assert.Contains(code["c.go"], "\npackage c_go\n")
+	assert.Contains(code["a.go"], "\"github.com/demo/bpb\"\n")
+	assert.Contains(code["a.go"], "\"github.com/demo/c-go\"\n")
+
+	// import path is inferred from go.mod above the output directory
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/demo // demo\n\ngo 1.16\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	delete(files, "c.breeze")
+	files["a.breeze"] = "package test.a;\nmessage A {\n    test.b.B b = 1;\n}\n"
+	files["b.breeze"] = "package test.b;\nmessage B {\n    int32 id = 1;\n}\n"
+	config := &generator.Config{CodeTemplates: "go", WritePath: filepath.Join(dir, "gen"), Options: map[string]string{core.WithPackageDir: "true"}}
+	_, err = generator.GeneratePath(writeSchemaFiles(t, dir, files), config)
+	assert.Nil(err)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "gen", "go", "test", "a", "a.go"))
+	assert.Nil(err)
+	assert.Contains(string(content), "\"example.com/demo/gen/go/test/b\"\n")
+
+	// go_package_prefix takes precedence over go.mod
+	config = &generator.Config{CodeTemplates: "go", WritePath: filepath.Join(dir, "gen"), Options: map[string]string{core.WithPackageDir: "true", "go.package_prefix": "github.com/demo"}}
+	_, err = generator.GeneratePath(filepath.Join(dir, "schemas"), config)
+	assert.Nil(err)
+	content, err = ioutil.ReadFile(filepath.Join(dir, "gen", "go", "test", "a", "a.go"))
+	assert.Nil(err)
+	assert.Contains(string(content), "\"github.com/demo/test/b\"\n")
+
+	// cached code is regenerated after the module path in go.mod changes
+	cache, _ := generator.NewCache("")
+	config = &generator.Config{CodeTemplates: "go", WritePath: filepath.Join(dir, "gen"), Options: map[string]string{core.WithPackageDir: "true"}, Cache: cache}
+	_, err = generator.GeneratePath(filepath.Join(dir, "schemas"), config)
+	assert.Nil(err)
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/other\n\ngo 1.16\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = generator.GeneratePath(filepath.Join(dir, "schemas"), config)
+	assert.Nil(err)
+	content, err = ioutil.ReadFile(filepath.Join(dir, "gen", "go", "test", "a", "a.go"))
+	assert.Nil(err)
+	assert.Contains(string(content), "\"example.com/other/gen/go/test/b\"\n")
+	hits, misses := cache.Stats()
+	assert.Equal(0, hits)
+	assert.Equal(4, misses)
+}
+
+func TestGoImportAlias(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{
+		"a.breeze":   "package test.a;\nmessage A {\n    test.b.B b = 1;\n    other.b.B c = 2;\n    x.a_b.B d = 3;\n    a.b.B e = 4;\n    map<string, array<test.b.B>> f = 5;\n}\n",
+		"b.breeze":   "package test.b;\nmessage B {\n    int32 id = 1;\n}\n",
+		"ob.breeze":  "package other.b;\nmessage B {\n    int32 id = 1;\n}\n",
+		"xab.breeze": "package x.a_b;\nmessage B {\n    int32 id = 1;\n}\n",
+		"ab.breeze":  "package a.b;\nmessage B {\n    int32 id = 1;\n}\n",
+	}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go"})
+	assert.Nil(err)
+	for _, s := range []string{"	test_b \"test/b\"\n", "	other_b \"other/b\"\n", "	x_a_b \"x/a_b\"\n", "	a_b2 \"a/b\"\n",
+		"	F map[string][]*test_b.B\n", "a.B = &test_b.B{}"} {
+		assert.Contains(code["a.go"], s)
+	}
+	code2, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go"})
+	assert.Nil(err)
+	assert.Equal(code["a.go"], code2["a.go"])
+
+	// packages with the same name in the tests of main
+	dir := newGoModule(t)
+	config := &generator.Config{CodeTemplates: "go", WritePath: dir, Options: map[string]string{core.WithPackageDir: "true"}}
+	_, err = generator.GeneratePath(filepath.Join("..", "main", "tests"), config)
+	assert.Nil(err)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "go", "java", "util", "Demo.go"))
+	assert.Nil(err)
+	assert.Contains(string(content), "	java1_util \"example.com/demo/go/java1/util\"\n")
+	assert.Contains(string(content), "	java2_util \"example.com/demo/go/java2/util\"\n")
+	goRun(t, dir, "vet", "./...")
+}
+
+func TestGoService(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{
+		"s.breeze": "package test.s;\nmessage M {\n    int32 id = 1;\n}\nenum E {\n    A = 1;\n}\n" +
+			"service S {\n    get(M m, E e, map<string, array<M>> ms, array<E> es, bytes type)M;\n    getEnum(int64 id)E;\n" +
+			"    getMap(string name)map<string, M>;\n    getArray(array<string> names)array<M>;\n    ping();\n" +
+			"    echo(map<string, map<int32, array<E>>> m, array<map<string, M>> a, M nilMsg)map<string, array<M>>;\n}\n",
+		"o.breeze": "package test.o;\nservice O {\n    get(test.s.M m)test.s.E;\n}\n",
+	}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go"})
+	assert.Nil(err)
+	s := code["s.go"]
+	assert.Contains(s, "\"context\"\n")
+	assert.Contains(s, "	Get(ctx context.Context, m *M, e_ *E, ms map[string][]*M, es []*E, type_ []byte) (*M, error)\n")
+	assert.Contains(s, "	Ping(ctx context.Context) error\n")
+	assert.Contains(s, "func NewSClient(caller SCaller) *SClient {\n")
+	assert.Contains(s, "	reply := new(E)\n")
+	assert.Contains(s, "		e_ := new(E)\n		if v, e := breeze.ReadValue(buf, e_); e != nil || v == nil {\n") // e is the decode error
+	assert.Contains(s, "func (c *SClient) GetAsync(ctx context.Context, m *M, e_ *E, ms map[string][]*M, es []*E, type_ []byte) *SGetFuture {\n")
+	assert.Contains(s, "func (f *SPingFuture) Get() error {\n")
+	assert.Contains(s, "func RegisterSProvider(registrar SRegistrar, impl S) error {\n")
+	assert.Contains(s, "	return \"test.s.S\"\n")
+
+	// generated code compiles against breeze-go
+	dir := generateGoModule(t, files)
+	goRun(t, dir, "vet", "./...")
+
+	// round trip between client and provider through an in-process fake transport
+	if err := ioutil.WriteFile(filepath.Join(dir, "go", "test", "s", "s_test.go"), []byte(goServiceTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goRun(t, dir, "test", "./go/test/s")
+	assert.NotContains(s, "SMotanClient")
+}
+
+func TestGoMotan(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{
+		"s.breeze": "package test.s;\nmessage M {\n    int32 id = 1;\n}\nenum E {\n    A = 1;\n}\n" +
+			"service S {\n    get(M m, E e, map<string, array<M>> ms, array<E> es, bytes type)M;\n    getEnum(int64 id)E;\n" +
+			"    getMap(string name)map<string, M>;\n    getArray(array<string> names)array<M>;\n    ping();\n" +
+			"    echo(map<string, map<int32, array<E>>> m, array<map<string, M>> a, M nilMsg)map<string, array<M>>;\n}\n",
+	}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go", Options: map[string]string{"go.motan": "true"}})
+	assert.Nil(err)
+	s := code["s.go"]
+	assert.Contains(s, "	motan \"github.com/weibocom/motan-go\"\n	motancore \"github.com/weibocom/motan-go/core\"\n")
+	assert.Contains(s, "func NewSMotanClient(client *motan.MCClient) *SMotanClient {\n")
+	assert.Contains(s, "func (c *SMotanClient) GetAsync(ctx context.Context, m *M, e_ *E, ms map[string][]*M, es []*E, type_ []byte) *SGetFuture {\n")
+	assert.Contains(s, "const SMotanProviderName = \"breeze.test.s.S\"\n")
+	assert.Contains(s, "func RegisterSMotanProvider(factory motancore.ExtensionFactory) {\n")
+	assert.Contains(s, "func (f *SPingFuture) GetWithTimeout(timeout time.Duration) error {\n")
+	assert.Contains(s, "func (f *SGetEnumFuture) OnDone(callback func(*E, error)) {\n")
+
+	// generated adapters compile and work with the stand-in of motan-go in testdata, which has the same api as motan-go
+	dir := newGoModule(t)
+	motan, err := filepath.Abs(filepath.Join("testdata", "motan-go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	goMod, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	goMod = append(goMod, "require github.com/weibocom/motan-go v0.0.0\n\nreplace github.com/weibocom/motan-go => "+motan+"\n"...)
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), goMod, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := &generator.Config{CodeTemplates: "go", WritePath: dir, Options: map[string]string{core.WithPackageDir: "true", "go.motan": "true"}}
+	_, err = generator.GeneratePath(writeSchemaFiles(t, dir, files), config)
+	assert.Nil(err)
+	goRun(t, dir, "vet", "./...")
+	if err := ioutil.WriteFile(filepath.Join(dir, "go", "test", "s", "s_test.go"), []byte(goServiceTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go", "test", "s", "motan_test.go"), []byte(goMotanTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goRun(t, dir, "test", "./go/test/s")
+}
+
+const goMotanTest = `package s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/weibocom/motan-go"
+	motancore "github.com/weibocom/motan-go/core"
+)
+
+// caller sends arguments encoded by SClient to a motan-go provider
+type caller struct {
+	provider motancore.Provider
+}
+
+func (c caller) Call(ctx context.Context, service string, method string, args []byte) ([]byte, error) {
+	request := &motancore.MotanRequest{Method: method, Arguments: []interface{}{&motancore.DeserializableValue{Body: args}}}
+	response := c.provider.Call(request)
+	if e := response.GetException(); e != nil {
+		return nil, errors.New(e.ErrMsg)
+	}
+	return motancore.SerializeMulti([]interface{}{response.GetValue()})
+}
+
+func TestMotanRoundTrip(t *testing.T) {
+	RegisterSMotanProvider(motan.GetDefaultExtFactory())
+	url := &motancore.URL{Path: "test.s.S", Parameters: map[string]string{"provider": SMotanProviderName}}
+	provider := motan.GetDefaultExtFactory().GetProvider(url)
+	if provider.IsAvailable() {
+		t.Fatal("provider without implementation is available")
+	}
+	provider.SetService(impl{})
+	client := NewSMotanClient(motan.NewClient(provider))
+	ctx := context.Background()
+	e := EA
+	m, err := client.Get(ctx, &M{Id: 10}, &e, nil, nil, []byte("abc"))
+	if err != nil || m.Id != 10+1+3 {
+		t.Errorf("get fail: %v, %v", m, err)
+	}
+	if _, err = client.GetEnum(ctx, -1); err == nil || err.Error() != "negative id" {
+		t.Errorf("error is expected: %v", err)
+	}
+	if echo, err := client.Echo(ctx, nil, nil, nil); err != nil || len(echo) != 0 {
+		t.Errorf("echo with nil message fail: %v, %v", echo, err)
+	}
+	if err = client.Ping(ctx); err != nil {
+		t.Errorf("ping fail: %v", err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err = client.Ping(timeout); err != context.DeadlineExceeded {
+		t.Errorf("deadline exceeded is expected: %v", err)
+	}
+	if err = client.PingAsync(ctx).GetWithTimeout(10 * time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("deadline exceeded is expected: %v", err)
+	}
+	done := make(chan *E, 1)
+	client.GetEnumAsync(ctx, 1).OnDone(func(result *E, err error) {
+		if err != nil {
+			t.Errorf("get enum fail: %v", err)
+		}
+		done <- result
+	})
+	if result := <-done; result == nil || *result != EA {
+		t.Errorf("get enum fail: %v", result)
+	}
+	if err = motan.NewClient(provider).Call("unknown", nil, nil); err == nil {
+		t.Errorf("unknown method error is expected")
+	}
+
+	// collections of messages and enums encoded by the generated client are decoded by the motan-go provider
+	m, err = NewSClient(caller{provider}).Get(ctx, &M{Id: 10}, &e, map[string][]*M{"a": {{Id: 1}, {Id: 2}}}, []*E{&e}, []byte("abc"))
+	if err != nil || m.Id != 10+1+2+1+3 {
+		t.Errorf("get by motan-go provider fail: %v, %v", m, err)
+	}
+
+	// the context of implementation carries the request and the request timeout of url
+	contexts := make(chan context.Context, 1)
+	url = &motancore.URL{Path: "test.s.S", Parameters: map[string]string{"requestTimeout": "1000"}}
+	request := &motancore.MotanRequest{Method: "ping"}
+	request.SetAttachment("trace", "abc")
+	if response := NewSMotanProvider(url, contextImpl{contexts: contexts}).Call(request); response.GetException() != nil {
+		t.Fatalf("ping fail: %v", response.GetException())
+	}
+	ctx = <-contexts
+	if r, ok := SMotanRequest(ctx); !ok || r.GetAttachment("trace") != "abc" {
+		t.Errorf("request of context is expected: %v", r)
+	}
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Second {
+		t.Errorf("deadline of request timeout is expected: %v", deadline)
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("context is expected to be canceled after call: %v", ctx.Err())
+	}
+}
+
+// contextImpl sends the context of ping to contexts
+type contextImpl struct {
+	impl
+	contexts chan context.Context
+}
+
+func (c contextImpl) Ping(ctx context.Context) error {
+	c.contexts <- ctx
+	return nil
+}
+`
+
+const goServiceTest = `package s
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type server map[string]func(ctx context.Context, method string, args []byte) ([]byte, error)
+
+func (s server) Register(service string, invoke func(ctx context.Context, method string, args []byte) ([]byte, error)) error {
+	s[service] = invoke
+	return nil
+}
+
+func (s server) Call(ctx context.Context, service string, method string, args []byte) ([]byte, error) {
+	invoke, ok := s[service]
+	if !ok {
+		return nil, errors.New("unknown service " + service)
+	}
+	return invoke(ctx, method, append([]byte(nil), args...))
+}
+
+type impl struct{}
+
+func (impl) Get(ctx context.Context, m *M, e *E, ms map[string][]*M, es []*E, type_ []byte) (*M, error) {
+	return &M{Id: m.Id + int32(*e) + int32(len(ms["a"])) + int32(len(es)) + int32(len(type_))}, nil
+}
+
+func (impl) GetArray(ctx context.Context, names []string) ([]*M, error) {
+	result := make([]*M, 0, len(names))
+	for i := range names {
+		result = append(result, &M{Id: int32(i)})
+	}
+	return result, nil
+}
+
+func (impl) GetEnum(ctx context.Context, id int64) (*E, error) {
+	if id < 0 {
+		return nil, errors.New("negative id")
+	}
+	e := EA
+	return &e, nil
+}
+
+func (impl) GetMap(ctx context.Context, name string) (map[string]*M, error) {
+	return map[string]*M{name: {Id: 3}}, nil
+}
+
+func (impl) Echo(ctx context.Context, m map[string]map[int32][]*E, a []map[string]*M, nilMsg *M) (map[string][]*M, error) {
+	if nilMsg != nil {
+		return nil, errors.New("nil message is expected")
+	}
+	result := map[string][]*M{}
+	for k, v := range m {
+		for _, es := range v {
+			result[k] = append(result[k], &M{Id: int32(len(es))})
+		}
+	}
+	for _, ms := range a {
+		for k, v := range ms {
+			result[k] = append(result[k], v)
+		}
+	}
+	return result, nil
+}
+
+func (impl) Ping(ctx context.Context) error {
+	time.Sleep(100 * time.Millisecond)
+	return nil
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := server{}
+	if err := RegisterSProvider(s, impl{}); err != nil {
+		t.Fatal(err)
+	}
+	client := NewSClient(s)
+	ctx := context.Background()
+	e := EA
+	m, err := client.Get(ctx, &M{Id: 10}, &e, map[string][]*M{"a": {{Id: 1}, {Id: 2}}}, []*E{&e}, []byte("abc"))
+	if err != nil || m.Id != 10+1+2+1+3 {
+		t.Errorf("get fail: %v, %v", m, err)
+	}
+	ms, err := client.GetArray(ctx, []string{"a", "b"})
+	if err != nil || len(ms) != 2 || ms[1].Id != 1 {
+		t.Errorf("get array fail: %v, %v", ms, err)
+	}
+	if result, err := client.GetEnum(ctx, 1); err != nil || *result != EA {
+		t.Errorf("get enum fail: %v, %v", result, err)
+	}
+	if _, err = client.GetEnum(ctx, -1); err == nil || err.Error() != "negative id" {
+		t.Errorf("error is expected: %v", err)
+	}
+	if result, err := client.GetMapAsync(ctx, "x").Get(); err != nil || result["x"].Id != 3 {
+		t.Errorf("get map fail: %v, %v", result, err)
+	}
+	echo, err := client.Echo(ctx, map[string]map[int32][]*E{"a": {1: {&e, &e}}, "b": {}}, []map[string]*M{{"c": {Id: 5}}, {}}, nil)
+	if err != nil || len(echo) != 2 || echo["a"][0].Id != 2 || echo["c"][0].Id != 5 {
+		t.Errorf("echo fail: %v, %v", echo, err)
+	}
+	if err = client.Ping(ctx); err != nil {
+		t.Errorf("ping fail: %v", err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err = client.PingAsync(timeout).Get(); err != context.DeadlineExceeded {
+		t.Errorf("deadline exceeded is expected: %v", err)
+	}
+	if _, err = NewSProvider(impl{}).Invoke(ctx, "unknown", nil); err == nil {
+		t.Errorf("unknown method error is expected")
+	}
+	if _, err = NewSProvider(impl{}).Invoke(ctx, "getEnum", []byte{0xff}); err == nil {
+		t.Errorf("decode error is expected")
+	}
+}
+`
+
+func TestGoJSON(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{"j.breeze": "package test.j;\nenum Color {\n    RED = 1;\n}\n" +
+		"message User(go_json_tag = snake) {\n    string userName = 1 (tag.db = user_name, tag.yaml = user);\n    Color favoriteColor = 2;\n    int32 id = 3 (tag.json = -);\n}\n" +
+		"message Group {\n    string groupName = 1 (tag.db = name);\n}\n"}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go"})
+	assert.Nil(err)
+	assert.Contains(code["j.go"], "	UserName string `json:\"user_name\" db:\"user_name\" yaml:\"user\"`\n")
+	assert.Contains(code["j.go"], "	Id int32 `json:\"-\"`\n")
+	assert.Contains(code["j.go"], "	GroupName string `db:\"name\"`\n")
+	assert.Contains(code["j.go"], "	\"encoding/json\"\n	\"errors\"\n")
+
+	code, _, err = generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go", Options: map[string]string{"go.json_tag": "camel"}})
+	assert.Nil(err)
+	assert.Contains(code["j.go"], "	GroupName string `json:\"groupName\" db:\"name\"`\n")
+	_, _, err = generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go", Options: map[string]string{"go.json_tag": "kebab"}})
+	assert.NotNil(err)
+
+	// field options are described
+	dir := newGoModule(t)
+	set, err := generator.Describe([]string{writeSchemaFiles(t, dir, files)}, nil)
+	assert.Nil(err)
+	assert.Equal(map[string]string{"tag.db": "user_name", "tag.yaml": "user"}, set.Schemas[0].Messages[2].Fields[0].Options)
+	data, err := descriptor.MarshalBinary(set)
+	assert.Nil(err)
+	decoded, err := descriptor.UnmarshalBinary(data)
+	assert.Nil(err)
+	assert.Equal(set.Schemas[0].Messages, decoded.Schemas[0].Messages)
+
+	config := &generator.Config{CodeTemplates: "go", WritePath: dir, Options: map[string]string{core.WithPackageDir: "true"}}
+	_, err = generator.GeneratePath(filepath.Join(dir, "schemas"), config)
+	assert.Nil(err)
+	if err := ioutil.WriteFile(filepath.Join(dir, "go", "test", "j", "j_test.go"), []byte(goJSONTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goRun(t, dir, "test", "./go/test/j")
+}
+
+const goJSONTest = `package j
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	c := ColorRED
+	data, err := json.Marshal(&User{UserName: "a", FavoriteColor: &c, Id: 1})
+	if err != nil || string(data) != ` + "`" + `{"user_name":"a","favorite_color":"RED"}` + "`" + ` {
+		t.Errorf("marshal fail: %s, %v", data, err)
+	}
+	u := &User{}
+	if err = json.Unmarshal(data, u); err != nil || u.UserName != "a" || *u.FavoriteColor != ColorRED {
+		t.Errorf("unmarshal fail: %v, %v", u, err)
+	}
+	if err = json.Unmarshal([]byte(` + "`" + `{"favorite_color":1}` + "`" + `), u); err != nil || *u.FavoriteColor != ColorRED {
+		t.Errorf("unmarshal number fail: %v, %v", u, err)
+	}
+	if err = json.Unmarshal([]byte(` + "`" + `{"favorite_color":"BLUE"}` + "`" + `), u); err == nil {
+		t.Errorf("unknown name should fail")
+	}
+	if err = json.Unmarshal([]byte(` + "`" + `{"favorite_color":2}` + "`" + `), u); err == nil {
+		t.Errorf("unknown number should fail")
+	}
+	if data, err = json.Marshal(Color(2)); err != nil || string(data) != "2" {
+		t.Errorf("unknown number should be marshaled as number: %s, %v", data, err)
+	}
+}
+`
+
+func TestGoEqualClone(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{"q.breeze": "package test.q;\nenum E {\n    A = 1;\n    B = 2;\n}\n" +
+		"message M {\n    int32 id = 1;\n    bytes data = 2;\n    E e = 3;\n    M next = 4;\n    map<string, array<M>> ms = 5;\n" +
+		"    array<map<int32, E>> es = 6;\n    map<string, bytes> bs = 7;\n    array<string> names = 8;\n}\n"}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go"})
+	assert.Nil(err)
+	assert.Contains(code["q.go"], "func (m *M) Equal(other *M) bool {\n")
+	assert.Contains(code["q.go"], "func (m *M) Clone() *M {\n")
+	assert.Contains(code["q.go"], "func (m *M) Reset() {\n	*m = M{}\n}\n")
+
+	dir := generateGoModule(t, files)
+	if err := ioutil.WriteFile(filepath.Join(dir, "go", "test", "q", "q_test.go"), []byte(goEqualCloneTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goRun(t, dir, "test", "./go/test/q")
+}
+
+const goEqualCloneTest = `package q
+
+import (
+	"testing"
+
+	breeze "github.com/weibreeze/breeze-go"
+)
+
+func TestEqualClone(t *testing.T) {
+	a, b := EA, EB
+	m := &M{Id: 1, Data: []byte("abc"), E: &a, Next: &M{Id: 2}, Ms: map[string][]*M{"x": {{Id: 3}, nil}, "y": nil},
+		Es: []map[int32]*E{{1: &b, 2: nil}, nil}, Bs: map[string][]byte{"z": nil, "w": []byte("w")}, Names: []string{"n"}}
+	cloned := m.Clone()
+	if !m.Equal(cloned) || !cloned.Equal(m) {
+		t.Fatalf("clone is not equal: %v", cloned)
+	}
+	cloned.Data[0] = 'x'
+	*cloned.Es[0][1] = EA
+	cloned.Ms["x"][0].Id = 4
+	cloned.Names[0] = "o"
+	if string(m.Data) != "abc" || *m.Es[0][1] != EB || m.Ms["x"][0].Id != 3 || m.Names[0] != "n" || m.Equal(cloned) {
+		t.Errorf("clone is not deep: %v", m)
+	}
+	if len(cloned.Ms) != 2 || len(cloned.Es[0]) != 2 || len(cloned.Bs) != 2 {
+		t.Errorf("nil elements are not cloned: %v", cloned)
+	}
+
+	// nil and empty collections are equal, so a decoded message equals the original one
+	m = &M{Id: 1, Ms: map[string][]*M{}, Names: []string{}, Next: &M{Data: []byte{}}}
+	buf := breeze.NewBuffer(64)
+	if err := breeze.WriteValue(buf, m); err != nil {
+		t.Fatal(err)
+	}
+	decoded := &M{}
+	if _, err := breeze.ReadValue(breeze.CreateBuffer(buf.Bytes()), decoded); err != nil || !decoded.Equal(m) {
+		t.Errorf("decoded message is not equal: %v, %v", decoded, err)
+	}
+	if decoded.Equal(&M{Id: 1}) || (*M)(nil).Equal(m) || !(*M)(nil).Equal(nil) || (*M)(nil).Clone() != nil {
+		t.Errorf("wrong equal of nil messages")
+	}
+	decoded.Reset()
+	if !decoded.Equal(&M{}) {
+		t.Errorf("reset fail: %v", decoded)
+	}
+}
+`
+
+func TestGoReuse(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{"r.breeze": "package test.r;\noption go_reuse = true;\nenum E {\n    A = 1;\n    B = 2;\n}\n" +
+		"message M {\n    int32 id = 1;\n    bytes data = 2;\n    E e = 3;\n    M next = 4;\n    map<string, array<M>> ms = 5;\n" +
+		"    array<map<int32, E>> es = 6;\n    map<string, bytes> bs = 7;\n    array<string> names = 8;\n    array<byte> bb = 9;\n" +
+		"    test.o.Item item = 10;\n}\n",
+		// nested types of messages in other schemas are resolved in their own schemas
+		"o.breeze": "package test.o;\nenum Kind {\n    X = 1;\n}\nmessage Tag {\n    string v = 1;\n}\n" +
+			"message Item {\n    Kind kind = 1;\n    array<Item> children = 2;\n    map<string, Tag> tags = 3;\n}\n"}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go"})
+	assert.Nil(err)
+	assert.Contains(code["r_test.go"], "Item: &test_o.Item{Kind: func() *test_o.Kind { v := test_o.Kind(1); return &v }(), "+
+		"Children: []*test_o.Item{}, Tags: map[string]*test_o.Tag{\"breeze\": &test_o.Tag{V: \"breeze\"}}}")
+	assert.Contains(code["r.go"], "func AcquireM() *M {\n")
+	assert.Contains(code["r.go"], "func ReleaseM(m *M) {\n")
+	assert.Contains(code["r_test.go"], "func BenchmarkMReadFromInPlace(b *testing.B) {\n")
+
+	dir := generateGoModule(t, files)
+	if err := ioutil.WriteFile(filepath.Join(dir, "go", "test", "r", "pool_test.go"), []byte(goReuseTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goRun(t, dir, "test", "-bench", ".", "-benchtime", "1x", "./go/test/r")
+}
+
+const goReuseTest = `package r
+
+import (
+	"testing"
+
+	breeze "github.com/weibreeze/breeze-go"
+)
+
+func encode(t *testing.T, m *M) []byte {
+	buf := breeze.NewBuffer(256)
+	if err := breeze.WriteValue(buf, m); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReuse(t *testing.T) {
+	a, b := EA, EB
+	big := &M{Id: 1, Data: []byte("abc"), E: &a, Next: &M{Id: 2, Names: []string{"x"}}, Ms: map[string][]*M{"x": {{Id: 3}, {Id: 4}}, "y": {}},
+		Es: []map[int32]*E{{1: &b, 2: &a}, {3: &a}}, Bs: map[string][]byte{"w": []byte("w")}, Names: []string{"n", "o", "p"}, Bb: []byte{1, 2}}
+	small := &M{Id: 5, Ms: map[string][]*M{"z": {{Id: 6}}}, Es: []map[int32]*E{{4: &b}}, Names: []string{"q"}}
+	m := AcquireM()
+	defer ReleaseM(m)
+	for i, expect := range []*M{big, small, big, small} {
+		m.Reset()
+		if err := breeze.ReadByMessage(breeze.CreateBuffer(encode(t, expect)), m); err != nil {
+			t.Fatal(err)
+		}
+		if !m.Equal(expect) {
+			t.Fatalf("decoded message %d is not equal: %v", i, m)
+		}
+		decoded := &M{}
+		if _, err := breeze.ReadValue(breeze.CreateBuffer(encode(t, expect)), decoded); err != nil || !decoded.Equal(m) {
+			t.Fatalf("message %d decoded by ReadValue is not equal: %v, %v", i, decoded, err)
+		}
+	}
+	names, es := &m.Names[:1][0], m.Es[0]
+	m.Reset()
+	if err := breeze.ReadByMessage(breeze.CreateBuffer(encode(t, big)), m); err != nil || !m.Equal(big) {
+		t.Fatalf("decoded message is not equal: %v, %v", m, err)
+	}
+	if names != &m.Names[0] || len(es) != len(big.Es[0]) {
+		t.Errorf("memory of fields is not reused")
+	}
+
+	m.Reset()
+	if m.Id != 0 || m.Data != nil || m.E != nil || m.Next != nil || len(m.Ms) != 0 || len(m.Es) != 0 || len(m.Names) != 0 || len(m.Bb) != 0 {
+		t.Errorf("message is not reset: %v", m)
+	}
+	if cap(m.Names) < 3 || m.Ms == nil {
+		t.Errorf("memory of fields is released: %v", m)
+	}
+}
+`
+
+func TestGoSplitFiles(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{
+		"s.breeze": "package test.s;\noption go_split_files = true;\noption with_descriptor = true;\nenum Status {\n    Ok = 1;\n}\n" +
+			"message UserInfo {\n    int32 id = 1;\n    Status status = 2;\n    test.o.Other other = 3;\n}\n" +
+			"message ForLinux {\n    string name = 1;\n}\nmessage TableTest {\n    map<int32, Status> m = 1;\n}\n" +
+			"service UserService {\n    get(int32 id, test.o.Other o)UserInfo;\n}\n",
+		"o.breeze": "package test.o;\nmessage Other {\n    string name = 1;\n}\n",
+	}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "go"})
+	assert.Nil(err)
+	assert.Contains(code, "o.go")
+	assert.NotContains(code, "s.go")
+	for _, name := range []string{"status.go", "user_info.go", "for_linux_breeze.go", "table_test_breeze.go", "user_service.go", "s_init.go"} {
+		assert.Contains(code, name)
+	}
+	// imports are computed per file
+	assert.NotContains(code["status.go"], "test/o")
+	assert.Contains(code["user_info.go"], "	test_o \"test/o\"\n")
+	assert.NotContains(code["for_linux_breeze.go"], "strconv")
+	assert.Contains(code["user_service.go"], "\"context\"\n")
+	assert.Contains(code["s_init.go"], "func init() {\n")
+	assert.Contains(code["s_init.go"], "var SBreezeDescriptor = []byte{")
+	assert.NotContains(code["user_info.go"], "func init() {\n")
+
+	// files of messages, services and registrations can not overwrite each other
+	for _, conflict := range []string{"message SInit {\n    int32 id = 1;\n}\n", "message USERInfo {\n    int32 id = 1;\n}\n"} {
+		_, _, err = generator.GeneratByFileContent(map[string]string{"s.breeze": files["s.breeze"] + conflict, "o.breeze": files["o.breeze"]}, &generator.Config{CodeTemplates: "go"})
+		if assert.NotNil(err) {
+			assert.Contains(err.Error(), "is generated by both")
+		}
+	}
+	// schemas of the same go package share a directory
+	_, _, err = generator.GeneratByFileContent(map[string]string{
+		"a.breeze": "package test.s;\noption go_split_files = true;\nmessage BInit {\n    int32 id = 1;\n}\n",
+		"b.breeze": "package test.s;\noption go_split_files = true;\nmessage B {\n    int32 id = 1;\n}\n",
+	}, &generator.Config{CodeTemplates: "go"})
+	if assert.NotNil(err) {
+		assert.Contains(err.Error(), "go file b_init.go is generated by both BInit of a.breeze and registrations of b.breeze")
+	}
+	_, _, err = generator.GeneratByFileContent(map[string]string{
+		"a.breeze": "package test.s;\noption go_split_files = true;\nmessage B {\n    int32 id = 1;\n}\n",
+		"b.breeze": "package test.s;\nmessage C {\n    int32 id = 1;\n}\n",
+	}, &generator.Config{CodeTemplates: "go"})
+	if assert.NotNil(err) {
+		assert.Contains(err.Error(), "go file b.go is generated by both B of a.breeze and schema b.breeze")
+	}
+	// init files are named in snake case
+	code, _, err = generator.GeneratByFileContent(map[string]string{"MyDemo.breeze": "package test.d;\noption go_split_files = true;\nmessage M {\n    int32 id = 1;\n}\n"}, &generator.Config{CodeTemplates: "go"})
+	assert.Nil(err)
+	assert.Contains(code, "my_demo_init.go")
+
+	dir := generateGoModule(t, files)
+	goRun(t, dir, "vet", "./...")
+}
+
+// writeSchemaFiles writes schema files into the directory `schemas` of dir, and returns the directory
+func writeSchemaFiles(t *testing.T, dir string, files map[string]string) string {
+	schemaDir := filepath.Join(dir, "schemas")
+	if err := os.MkdirAll(schemaDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(schemaDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return schemaDir
+}
+
+// newGoModule creates a temporary go module depending on breeze-go for generated go code.
+// the test is skipped if go or breeze-go in module cache is not available.
+func newGoModule(t *testing.T) string {
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	cache, err := exec.Command(goCmd, "env", "GOMODCACHE").Output()
+	if err != nil {
+		t.Skip("GOMODCACHE not found")
+	}
+	if _, err = os.Stat(filepath.Join(strings.TrimSpace(string(cache)), "github.com", "weibreeze", "breeze-go@v0.1.0")); err != nil {
+		t.Skip("breeze-go is not in module cache")
+	}
+	sum, err := ioutil.ReadFile(filepath.Join("..", "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var goSum strings.Builder
+	for _, line := range strings.Split(string(sum), "\n") {
+		if strings.HasPrefix(line, "github.com/weibreeze/breeze-go ") || strings.HasPrefix(line, "github.com/pkg/errors ") {
+			goSum.WriteString(line + "\n")
+		}
+	}
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/demo\n\ngo 1.16\n\nrequire github.com/weibreeze/breeze-go v0.1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.sum"), []byte(goSum.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// generateGoModule generates go code of schema files with package dirs into a go module created by newGoModule, and returns the module dir
+func generateGoModule(t *testing.T, files map[string]string) string {
+	dir := newGoModule(t)
+	config := &generator.Config{CodeTemplates: "go", WritePath: dir, Options: map[string]string{core.WithPackageDir: "true"}}
+	if _, err := generator.GeneratePath(writeSchemaFiles(t, dir, files), config); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// goRun runs a go command such as `vet ./...` in the module dir created by newGoModule
+func goRun(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("go %s fail: %s\n%s", strings.Join(args, " "), err, output)
+	}
+}
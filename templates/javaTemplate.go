@@ -2,6 +2,9 @@ package templates
 
 import (
 	"bytes"
+	"encoding/base64"
+	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -40,6 +43,18 @@ func (jt *JavaTemplate) Name() string {
 	return Java
 }
 
+//Options : options accepted by java template
+func (jt *JavaTemplate) Options() []*core.Option {
+	return []*core.Option{
+		{Name: "package", Key: core.JavaPackage, Description: "java package of generated classes, the package of schema is used if not set"},
+		withPackageDirOption,
+		withDescriptorOption,
+		keepUnknownEnumOption,
+		withTestsOption,
+		{Name: "test_dir", Key: core.JavaTestDir, Description: "directory of generated tests relative to the output directory, such as `../../test/java` for `src/main/java`. java tests are generated only if it is set, so main sources do not depend on JUnit"},
+	}
+}
+
 //GenerateCode : generate java code
 func (jt *JavaTemplate) GenerateCode(schema *core.Schema, context *core.Context) (contents map[string][]byte, err error) {
 	contents = make(map[string][]byte)
@@ -78,9 +93,222 @@ func (jt *JavaTemplate) GenerateCode(schema *core.Schema, context *core.Context)
 			}
 		}
 	}
+	desc, err := binaryDescriptor(schema, context)
+	if err != nil {
+		return nil, err
+	}
+	if desc != nil {
+		file, content := jt.generateDescriptor(schema, desc)
+		contents[file] = content
+	}
+	if testDir := schema.Options[core.JavaTestDir]; testDir != "" && withTests(schema) {
+		if file, content := jt.generateTest(schema, context); content != nil {
+			contents[path.Join(filepath.ToSlash(testDir), file)] = content
+		}
+	}
 	return contents, nil
 }
 
+// generateTest generates a JUnit test which encodes and decodes messages with pseudo-random values.
+// it returns nil content if schema has no message.
+func (jt *JavaTemplate) generateTest(schema *core.Schema, context *core.Context) (file string, content []byte) {
+	messages := make([]*core.Message, 0, len(schema.Messages))
+	importStr := []string{"import java.util.*;\n"}
+	for _, message := range sortMessages(schema) {
+		if !message.IsEnum {
+			messages = append(messages, message)
+			for _, field := range message.Fields {
+				importStr = jt.getTypeImport(field.Type, context, importStr)
+			}
+		}
+	}
+	if len(messages) == 0 {
+		return "", nil
+	}
+	buf := &bytes.Buffer{}
+	writeGenerateComment(buf, schema.Name)
+	pkg := getJavaPkg(schema)
+	if pkg != "" {
+		buf.WriteString("package " + pkg + ";\n\n")
+	}
+	// reflection classes and Message are not imported, they may have the same names as messages
+	buf.WriteString("import com.weibo.breeze.*;\nimport org.junit.Test;\n\nimport java.nio.charset.StandardCharsets;\n")
+	for _, t := range sortUnique(importStr) {
+		buf.WriteString(t)
+	}
+	buf.WriteString("\nimport static org.junit.Assert.assertTrue;\n\n")
+	name := schemaClassName(schema) + "BreezeTest"
+	buf.WriteString("public class " + name + " {\n")
+	for _, message := range messages {
+		buf.WriteString("    @Test\n    public void test" + message.Name + "RoundTrip() throws Exception {\n        Random r = new Random(1);\n")
+		buf.WriteString("        for (int i = 0; i < 20; i++) {\n            " + message.Name + " message = random" + message.Name + "(r, 0);\n")
+		buf.WriteString("            BreezeBuffer buf = new BreezeBuffer(256);\n            BreezeWriter.writeObject(buf, message);\n            buf.flip();\n")
+		buf.WriteString("            " + message.Name + " decoded = BreezeReader.readObject(new BreezeBuffer(buf.getBytes()), " + message.Name + ".class);\n")
+		buf.WriteString("            assertTrue(\"decoded " + message.Name + " is not equal\", deepEquals(message, decoded));\n        }\n    }\n\n")
+	}
+	for _, message := range messages {
+		buf.WriteString("    // messages deeper than 2 are not set\n")
+		buf.WriteString("    static " + message.Name + " random" + message.Name + "(Random r, int depth) {\n        " + message.Name + " m = new " + message.Name + "();\n")
+		for _, field := range sortFields(message) {
+			value := "f" + strconv.Itoa(field.Index)
+			blank := "        "
+			if containsMessage(field.Type, schema, context) {
+				buf.WriteString("        if (depth < 2) {\n")
+				blank += "    "
+			}
+			jt.randomValue(buf, field.Type, jt.getTypeString(field.Type, false), value, blank, 1, schema, context)
+			buf.WriteString(blank + "m.set" + firstUpper(field.Name) + "(" + value + ");\n")
+			if blank != "        " {
+				buf.WriteString("        }\n")
+			}
+		}
+		buf.WriteString("        return m;\n    }\n\n")
+	}
+	buf.WriteString(`    // compares fields of messages, null and empty strings, bytes or collections are equal
+    static boolean deepEquals(Object a, Object b) throws IllegalAccessException {
+        if (isEmpty(a) || isEmpty(b)) {
+            return isEmpty(a) && isEmpty(b);
+        }
+        if (a instanceof byte[] && b instanceof byte[]) {
+            return Arrays.equals((byte[]) a, (byte[]) b);
+        }
+        if (a instanceof List && b instanceof List) {
+            List<?> x = (List<?>) a, y = (List<?>) b;
+            if (x.size() != y.size()) {
+                return false;
+            }
+            for (int i = 0; i < x.size(); i++) {
+                if (!deepEquals(x.get(i), y.get(i))) {
+                    return false;
+                }
+            }
+            return true;
+        }
+        if (a instanceof Map && b instanceof Map) {
+            Map<?, ?> x = (Map<?, ?>) a, y = (Map<?, ?>) b;
+            if (x.size() != y.size()) {
+                return false;
+            }
+            for (Map.Entry<?, ?> entry : x.entrySet()) {
+                if (!y.containsKey(entry.getKey()) || !deepEquals(entry.getValue(), y.get(entry.getKey()))) {
+                    return false;
+                }
+            }
+            return true;
+        }
+        if (a instanceof com.weibo.breeze.message.Message && a.getClass() == b.getClass()) {
+            for (java.lang.reflect.Field field : a.getClass().getDeclaredFields()) {
+                if (!java.lang.reflect.Modifier.isStatic(field.getModifiers())) {
+                    field.setAccessible(true);
+                    if (!deepEquals(field.get(a), field.get(b))) {
+                        return false;
+                    }
+                }
+            }
+            return true;
+        }
+        return a.equals(b);
+    }
+
+    static boolean isEmpty(Object o) {
+        return o == null || (o instanceof String && ((String) o).isEmpty()) || (o instanceof byte[] && ((byte[]) o).length == 0)
+                || (o instanceof Collection && ((Collection<?>) o).isEmpty()) || (o instanceof Map && ((Map<?, ?>) o).isEmpty());
+    }
+}
+`)
+	return withPackageDirByName(name, schema, pkg, false) + ".java", buf.Bytes()
+}
+
+// randomValue writes the statements which declare name of type tpStr with a pseudo-random value of type tp by r.
+// messages declared in other schemas are empty, because their random methods are not accessible.
+func (jt *JavaTemplate) randomValue(buf *bytes.Buffer, tp *core.Type, tpStr string, name string, blank string, recursion int, schema *core.Schema, context *core.Context) {
+	recStr := strconv.Itoa(recursion)
+	var value string
+	switch tp.Number {
+	case core.Bool:
+		value = "r.nextBoolean()"
+	case core.String:
+		value = "Long.toString(r.nextLong(), 36)"
+	case core.Byte:
+		value = "(byte) r.nextInt()"
+	case core.Bytes:
+		value = "Long.toString(r.nextLong(), 36).getBytes(StandardCharsets.UTF_8)"
+	case core.Int16:
+		value = "(short) r.nextInt()"
+	case core.Int32:
+		value = "r.nextInt()"
+	case core.Int64:
+		value = "r.nextLong()"
+	case core.Float32:
+		value = "r.nextFloat()"
+	case core.Float64:
+		value = "r.nextGaussian()"
+	case core.Array:
+		buf.WriteString(blank + tpStr + " " + name + " = new ArrayList<>();\n")
+		buf.WriteString(blank + "for (int i" + recStr + " = r.nextInt(3); i" + recStr + " > 0; i" + recStr + "--) {\n")
+		jt.randomValue(buf, tp.ValueType, jt.getTypeString(tp.ValueType, true), "v"+recStr, blank+"    ", recursion+1, schema, context)
+		buf.WriteString(blank + "    " + name + ".add(v" + recStr + ");\n" + blank + "}\n")
+		return
+	case core.Map:
+		buf.WriteString(blank + tpStr + " " + name + " = new HashMap<>();\n")
+		buf.WriteString(blank + "for (int i" + recStr + " = r.nextInt(3); i" + recStr + " > 0; i" + recStr + "--) {\n")
+		jt.randomValue(buf, tp.KeyType, jt.getTypeString(tp.KeyType, true), "k"+recStr, blank+"    ", recursion+1, schema, context)
+		jt.randomValue(buf, tp.ValueType, jt.getTypeString(tp.ValueType, true), "v"+recStr, blank+"    ", recursion+1, schema, context)
+		buf.WriteString(blank + "    " + name + ".put(k" + recStr + ", v" + recStr + ");\n" + blank + "}\n")
+		return
+	case core.Msg:
+		message := schemaMessage(tp, schema)
+		if message == nil {
+			fullName := tp.Name
+			if strings.Index(fullName, ".") < 0 {
+				fullName = schema.Package + "." + fullName
+			}
+			message = context.GetMessage(fullName)
+		}
+		switch {
+		case message != nil && message.IsEnum:
+			values := make([]string, 0, len(message.EnumValues))
+			for _, enumValue := range sortEnumValues(message) {
+				values = append(values, tpStr+"."+enumValue.Name)
+			}
+			value = "new " + tpStr + "[]{" + strings.Join(values, ", ") + "}[r.nextInt(" + strconv.Itoa(len(values)) + ")]"
+		case message != nil && schemaMessage(tp, schema) != nil:
+			value = "random" + tpStr + "(r, depth + 1)"
+		default:
+			value = "new " + tpStr + "()"
+		}
+	}
+	buf.WriteString(blank + tpStr + " " + name + " = " + value + ";\n")
+}
+
+// generateDescriptor generates a class holding the binary descriptor set of schema.
+// the descriptor is split into base64 strings, because a string constant of java is limited to 65535 bytes.
+func (jt *JavaTemplate) generateDescriptor(schema *core.Schema, desc []byte) (file string, content []byte) {
+	buf := &bytes.Buffer{}
+	writeGenerateComment(buf, schema.Name)
+	pkg := getJavaPkg(schema)
+	name := descriptorName(schema)
+	buf.WriteString("package " + pkg + ";\n\nimport java.util.Base64;\n\n")
+	buf.WriteString("public final class " + name + " {\n")
+	buf.WriteString("    private static final String[] PARTS = new String[]{\n")
+	encoded := base64.StdEncoding.EncodeToString(desc)
+	for len(encoded) > 0 {
+		size := 76
+		if size > len(encoded) {
+			size = len(encoded)
+		}
+		buf.WriteString("        \"" + encoded[:size] + "\",\n")
+		encoded = encoded[size:]
+	}
+	buf.WriteString("    };\n    private static final byte[] DESCRIPTOR;\n\n")
+	buf.WriteString("    static {\n        StringBuilder sb = new StringBuilder();\n        for (String part : PARTS) {\n            sb.append(part);\n        }\n")
+	buf.WriteString("        DESCRIPTOR = Base64.getDecoder().decode(sb.toString());\n    }\n\n")
+	buf.WriteString("    private " + name + "() {}\n\n")
+	buf.WriteString("    /**\n     * @return binary descriptor set of " + schema.Name + ", a breeze.descriptor.SchemaSet message encoded by breeze\n     */\n")
+	buf.WriteString("    public static byte[] getDescriptor() {\n        return DESCRIPTOR.clone();\n    }\n}\n")
+	return withPackageDirByName(name, schema, pkg, false) + ".java", buf.Bytes()
+}
+
 func (jt *JavaTemplate) generateEnum(schema *core.Schema, message *core.Message, context *core.Context) (file string, content []byte, err error) {
 	buf := &bytes.Buffer{}
 	writeGenerateComment(buf, schema.Name)
@@ -96,6 +324,10 @@ func (jt *JavaTemplate) generateEnum(schema *core.Schema, message *core.Message,
 	for _, value := range enumValues {
 		buf.WriteString("    " + value.Name + "(" + strconv.Itoa(value.Index) + "),\n")
 	}
+	keepUnknown := keepUnknownEnum(message)
+	if keepUnknown { // unknown numbers are read as UNRECOGNIZED, and raw numbers of enum fields are kept by messages
+		buf.WriteString("    UNRECOGNIZED(-1),\n")
+	}
 	buf.Truncate(buf.Len() - 2)
 	buf.WriteString(";\n\n")
 	fullName := schema.OrgPackage + "." + message.Name
@@ -108,6 +340,36 @@ func (jt *JavaTemplate) generateEnum(schema *core.Schema, message *core.Message,
 	//constructor
 	buf.WriteString("    " + message.Name + "(int number) { this.number = number; }\n\n")
 
+	// number lookup, name lookup and ordered values are provided by name(), valueOf(String) and values() of java enum
+	if keepUnknown {
+		buf.WriteString("    /**\n     * @return number of the value in schema, -1 for UNRECOGNIZED. raw numbers of enum fields are kept by messages\n     */\n")
+		buf.WriteString("    public int getNumber() { return number; }\n\n")
+	} else {
+		buf.WriteString("    /**\n     * @return number of the value in schema\n     */\n    public int getNumber() { return number; }\n\n")
+	}
+	buf.WriteString("    /**\n     * @return value of the number in schema, null if the number is unknown\n     */\n")
+	buf.WriteString("    public static " + message.Name + " valueOf(int number) {\n        switch (number) {\n")
+	for _, value := range enumValues {
+		buf.WriteString("            case " + strconv.Itoa(value.Index) + ":\n                return " + value.Name + ";\n")
+	}
+	buf.WriteString("        }\n        return null;\n    }\n\n")
+
+	if keepUnknown {
+		buf.WriteString("    /**\n     * @return value of the number in schema, UNRECOGNIZED if the number is unknown\n     */\n")
+		buf.WriteString("    public static " + message.Name + " forNumber(int number) {\n        " + message.Name + " value = valueOf(number);\n")
+		buf.WriteString("        return value != null ? value : UNRECOGNIZED;\n    }\n\n")
+		// the serializer hands raw numbers over to the message reading or writing the enum field right after or before it in the same thread,
+		// so a message always gets the number of its own field.
+		buf.WriteString("    private static final ThreadLocal<Integer> lastUnrecognizedNumber = new ThreadLocal<>();\n")
+		buf.WriteString("    private static final ThreadLocal<Integer> nextUnrecognizedNumber = new ThreadLocal<>();\n\n")
+		buf.WriteString("    /**\n     * used by messages to keep the raw number of an enum field which is just read as UNRECOGNIZED\n")
+		buf.WriteString("     * @return raw number of the UNRECOGNIZED value just read in current thread\n     */\n")
+		buf.WriteString("    public static int takeLastUnrecognizedNumber() {\n        Integer number = lastUnrecognizedNumber.get();\n")
+		buf.WriteString("        lastUnrecognizedNumber.remove();\n        return number == null ? -1 : number;\n    }\n\n")
+		buf.WriteString("    /**\n     * used by messages to write the kept raw number of an UNRECOGNIZED enum field, it must be called right before the field is written\n     */\n")
+		buf.WriteString("    public static void setNextUnrecognizedNumber(int number) {\n        nextUnrecognizedNumber.set(number);\n    }\n\n")
+	}
+
 	// enum serializer
 	buf.WriteString("    public static class " + message.Name + "Serializer implements Serializer<" + message.Name + "> {\n")
 	//names
@@ -115,18 +377,31 @@ func (jt *JavaTemplate) generateEnum(schema *core.Schema, message *core.Message,
 
 	//writeTo
 	buf.WriteString("        @Override\n        public void writeToBuf(" + message.Name + " obj, BreezeBuffer breezeBuffer) throws BreezeException {\n")
-	buf.WriteString("            BreezeWriter.writeMessage(breezeBuffer, () -> {\n                TYPE_INT32.writeMessageField(breezeBuffer, 1, obj.number);\n            });\n        }\n\n")
+	if keepUnknown { // UNRECOGNIZED values in lists, maps or written directly have no raw numbers, they can not be written
+		buf.WriteString("            Integer number = obj.number;\n            if (obj == UNRECOGNIZED) {\n")
+		buf.WriteString("                number = nextUnrecognizedNumber.get();\n                nextUnrecognizedNumber.remove();\n")
+		buf.WriteString("                if (number == null) {\n                    throw new BreezeException(\"raw number of UNRECOGNIZED " + message.Name + " is unknown, only enum fields of messages keep raw numbers\");\n                }\n            }\n")
+		buf.WriteString("            final int rawNumber = number;\n")
+		buf.WriteString("            BreezeWriter.writeMessage(breezeBuffer, () -> {\n                TYPE_INT32.writeMessageField(breezeBuffer, 1, rawNumber);\n            });\n        }\n\n")
+	} else {
+		buf.WriteString("            BreezeWriter.writeMessage(breezeBuffer, () -> {\n                TYPE_INT32.writeMessageField(breezeBuffer, 1, obj.number);\n            });\n        }\n\n")
+	}
 
 	//readFrom
-	buf.WriteString("        @Override\n        public " + message.Name + " readFromBuf(BreezeBuffer breezeBuffer) throws BreezeException {\n            int[] number = new int[]{-1};\n")
+	buf.WriteString("        @Override\n        public " + message.Name + " readFromBuf(BreezeBuffer breezeBuffer) throws BreezeException {\n            Integer[] number = new Integer[1];\n")
 	buf.WriteString("            BreezeReader.readMessage(breezeBuffer, (int breezeIndex) -> {\n                switch (breezeIndex) {\n")
 	buf.WriteString("                    case 1:\n                        number[0] = TYPE_INT32.read(breezeBuffer);\n                        break;\n")
 	buf.WriteString("                    default:\n                        BreezeReader.readObject(breezeBuffer, Object.class);\n                }\n            });\n")
+	buf.WriteString("            if (number[0] == null) {\n                throw new BreezeException(\"missing number of enum " + message.Name + "\");\n            }\n")
 	buf.WriteString("            switch (number[0]) {\n")
 	for _, value := range enumValues {
 		buf.WriteString("                case " + strconv.Itoa(value.Index) + ":\n                   return " + value.Name + ";\n")
 	}
-	buf.WriteString("            }\n            throw new BreezeException(\"unknown enum number:\" + number[0]);\n        }\n\n")
+	if keepUnknown {
+		buf.WriteString("            }\n            lastUnrecognizedNumber.set(number[0]);\n            return UNRECOGNIZED;\n        }\n\n")
+	} else {
+		buf.WriteString("            }\n            throw new BreezeException(\"unknown enum number:\" + number[0]);\n        }\n\n")
+	}
 
 	//interface methods
 	buf.WriteString("        @Override\n        public String[] getNames() { return names; }\n    }\n}\n")
@@ -190,6 +465,13 @@ func (jt *JavaTemplate) generateMessage(schema *core.Schema, message *core.Messa
 	for _, field := range fields {
 		buf.WriteString("    private " + jt.getTypeString(field.Type, false) + " " + field.Name + ";\n")
 	}
+	keepFields := make(map[string]string) // fields of enums keeping unknown numbers -> enum class
+	for _, field := range fields {
+		if isKeepUnknownEnum(field.Type, schema, context) {
+			keepFields[field.Name] = jt.getTypeString(field.Type, false)
+			buf.WriteString("    private int " + field.Name + "UnrecognizedNumber = -1; // raw number if " + field.Name + " is UNRECOGNIZED\n")
+		}
+	}
 	buf.WriteString("\n    static {\n        try {\n            breezeSchema.setName(\"" + schema.OrgPackage + "." + message.Name + "\")")
 	for _, field := range fields {
 		buf.WriteString("\n                    .putField(new Schema.Field(" + strconv.Itoa(field.Index) + ", \"" + field.Name + "\", \"" + field.Type.TypeString + "\"))")
@@ -206,6 +488,9 @@ func (jt *JavaTemplate) generateMessage(schema *core.Schema, message *core.Messa
 	//writeTo
 	buf.WriteString("    @Override\n    public void writeToBuf(BreezeBuffer breezeBuffer) throws BreezeException {\n        BreezeWriter.writeMessage(breezeBuffer, () -> {\n")
 	for _, field := range fields {
+		if enum, ok := keepFields[field.Name]; ok {
+			buf.WriteString("            if (" + field.Name + " == " + enum + ".UNRECOGNIZED) {\n                " + enum + ".setNextUnrecognizedNumber(" + field.Name + "UnrecognizedNumber);\n            }\n")
+		}
 		if field.Type.Number < core.Map {
 			buf.WriteString("            " + javaTypes[field.Type.Number].breezeType)
 		} else {
@@ -224,7 +509,11 @@ func (jt *JavaTemplate) generateMessage(schema *core.Schema, message *core.Messa
 		} else {
 			buf.WriteString(field.Name + "BreezeType")
 		}
-		buf.WriteString(".read(breezeBuffer);\n                    break;\n")
+		buf.WriteString(".read(breezeBuffer);\n")
+		if enum, ok := keepFields[field.Name]; ok {
+			buf.WriteString("                    " + field.Name + "UnrecognizedNumber = " + field.Name + " == " + enum + ".UNRECOGNIZED ? " + enum + ".takeLastUnrecognizedNumber() : -1;\n")
+		}
+		buf.WriteString("                    break;\n")
 	}
 	buf.WriteString("                default: //skip unknown field\n                    BreezeReader.readObject(breezeBuffer, Object.class);\n            }\n        });\n        return this;\n    }\n\n")
 
@@ -238,13 +527,188 @@ func (jt *JavaTemplate) generateMessage(schema *core.Schema, message *core.Messa
 	for _, field := range fields {
 		buf.WriteString("    public " + jt.getTypeString(field.Type, false) + " get" + firstUpper(field.Name) + "() { return " + field.Name + "; }\n\n")
 		buf.WriteString("    public " + message.Name + " set" + firstUpper(field.Name) + "(" + jt.getTypeString(field.Type, false) + " " + field.Name + ") { this." + field.Name + " = " + field.Name + "; return this;}\n\n")
+		if _, ok := keepFields[field.Name]; ok {
+			buf.WriteString("    public int get" + firstUpper(field.Name) + "UnrecognizedNumber() { return " + field.Name + "UnrecognizedNumber; }\n\n")
+		}
 	}
+	jt.writeObjectMethods(buf, message, fields, keepFields)
 	buf.Truncate(buf.Len() - 1)
 	buf.WriteString("}\n")
 
 	return withPackageDirByName(message.Name, schema, pkg, false) + ".java", buf.Bytes(), nil
 }
 
+// writeObjectMethods writes equals, hashCode and toString of a message which include all fields.
+// values of fields with option `sensitive = true` are hidden in toString.
+// java.util classes are fully qualified, so they will not conflict with messages of the same name.
+// byte arrays in lists and maps are compared, hashed and printed by content with the helpers from writeDeepMethods.
+func (jt *JavaTemplate) writeObjectMethods(buf *bytes.Buffer, message *core.Message, fields []*core.Field, keepFields map[string]string) {
+	equals := make([]string, 0, len(fields))
+	hashes := make([]string, 0, len(fields))
+	str := &bytes.Buffer{}
+	literal := message.Name + "{"
+	deep := false
+	for i, field := range fields {
+		name := "this." + field.Name
+		deepField := containsBytes(field.Type) && field.Type.Number != core.Bytes
+		switch field.Type.Number {
+		case core.Bool, core.Byte, core.Int16, core.Int32, core.Int64:
+			equals = append(equals, name+" == that."+field.Name)
+			hashes = append(hashes, name)
+		case core.Float32:
+			equals = append(equals, "Float.compare("+name+", that."+field.Name+") == 0")
+			hashes = append(hashes, name)
+		case core.Float64:
+			equals = append(equals, "Double.compare("+name+", that."+field.Name+") == 0")
+			hashes = append(hashes, name)
+		case core.Bytes:
+			equals = append(equals, "java.util.Arrays.equals("+name+", that."+field.Name+")")
+			hashes = append(hashes, "java.util.Arrays.hashCode("+name+")")
+		case core.Array, core.Map:
+			if deepField {
+				deep = true
+				equals = append(equals, "deepEquals("+name+", that."+field.Name+")")
+				hashes = append(hashes, "deepHashCode("+name+")")
+			} else {
+				equals = append(equals, "java.util.Objects.equals("+name+", that."+field.Name+")")
+				hashes = append(hashes, name)
+			}
+		default: // string, message and enum
+			equals = append(equals, "java.util.Objects.equals("+name+", that."+field.Name+")")
+			hashes = append(hashes, name)
+		}
+		if _, ok := keepFields[field.Name]; ok {
+			equals = append(equals, name+"UnrecognizedNumber == that."+field.Name+"UnrecognizedNumber")
+			hashes = append(hashes, name+"UnrecognizedNumber")
+		}
+
+		if i > 0 {
+			literal += ", "
+		}
+		if sensitive, _ := strconv.ParseBool(field.Options[core.Sensitive]); sensitive {
+			literal += field.Name + "=***"
+			continue
+		}
+		str.WriteString(strconv.Quote(literal+field.Name+"=") + " + ")
+		if field.Type.Number == core.Bytes {
+			str.WriteString("java.util.Arrays.toString(" + name + ") + ")
+		} else if deepField {
+			str.WriteString("deepToString(" + name + ") + ")
+		} else {
+			str.WriteString(name + " + ")
+		}
+		literal = ""
+	}
+	str.WriteString(strconv.Quote(literal + "}"))
+
+	buf.WriteString("    @Override\n    public boolean equals(Object o) {\n        if (this == o) {\n            return true;\n        }\n")
+	buf.WriteString("        if (o == null || getClass() != o.getClass()) {\n            return false;\n        }\n")
+	if len(equals) == 0 {
+		buf.WriteString("        return true;\n    }\n\n")
+	} else {
+		buf.WriteString("        " + message.Name + " that = (" + message.Name + ") o;\n")
+		buf.WriteString("        return " + strings.Join(equals, "\n                && ") + ";\n    }\n\n")
+	}
+	buf.WriteString("    @Override\n    public int hashCode() {\n        return java.util.Objects.hash(" + strings.Join(hashes, ", ") + ");\n    }\n\n")
+	buf.WriteString("    @Override\n    public String toString() {\n        return " + str.String() + ";\n    }\n\n")
+	if deep {
+		writeDeepMethods(buf)
+	}
+}
+
+// containsBytes reports whether values of the list or map type are byte arrays, directly or in nested lists and maps.
+func containsBytes(tp *core.Type) bool {
+	switch tp.Number {
+	case core.Bytes:
+		return true
+	case core.Array, core.Map:
+		return containsBytes(tp.ValueType)
+	}
+	return false
+}
+
+// writeDeepMethods writes the helpers used by equals, hashCode and toString of lists and maps containing byte arrays,
+// which are compared by reference in java collections.
+func writeDeepMethods(buf *bytes.Buffer) {
+	buf.WriteString(`    private static boolean deepEquals(Object a, Object b) {
+        if (a instanceof byte[] && b instanceof byte[]) {
+            return java.util.Arrays.equals((byte[]) a, (byte[]) b);
+        }
+        if (a instanceof java.util.List && b instanceof java.util.List) {
+            java.util.List<?> x = (java.util.List<?>) a;
+            java.util.List<?> y = (java.util.List<?>) b;
+            if (x.size() != y.size()) {
+                return false;
+            }
+            java.util.Iterator<?> it = y.iterator();
+            for (Object value : x) {
+                if (!deepEquals(value, it.next())) {
+                    return false;
+                }
+            }
+            return true;
+        }
+        if (a instanceof java.util.Map && b instanceof java.util.Map) {
+            java.util.Map<?, ?> x = (java.util.Map<?, ?>) a;
+            java.util.Map<?, ?> y = (java.util.Map<?, ?>) b;
+            if (x.size() != y.size()) {
+                return false;
+            }
+            for (java.util.Map.Entry<?, ?> entry : x.entrySet()) {
+                if (!y.containsKey(entry.getKey()) || !deepEquals(entry.getValue(), y.get(entry.getKey()))) {
+                    return false;
+                }
+            }
+            return true;
+        }
+        return java.util.Objects.equals(a, b);
+    }
+
+    private static int deepHashCode(Object o) {
+        if (o instanceof byte[]) {
+            return java.util.Arrays.hashCode((byte[]) o);
+        }
+        if (o instanceof java.util.List) {
+            int hash = 1;
+            for (Object value : (java.util.List<?>) o) {
+                hash = 31 * hash + deepHashCode(value);
+            }
+            return hash;
+        }
+        if (o instanceof java.util.Map) {
+            int hash = 0;
+            for (java.util.Map.Entry<?, ?> entry : ((java.util.Map<?, ?>) o).entrySet()) {
+                hash += java.util.Objects.hashCode(entry.getKey()) ^ deepHashCode(entry.getValue());
+            }
+            return hash;
+        }
+        return java.util.Objects.hashCode(o);
+    }
+
+    private static String deepToString(Object o) {
+        if (o instanceof byte[]) {
+            return java.util.Arrays.toString((byte[]) o);
+        }
+        if (o instanceof java.util.List) {
+            java.util.StringJoiner joiner = new java.util.StringJoiner(", ", "[", "]");
+            for (Object value : (java.util.List<?>) o) {
+                joiner.add(deepToString(value));
+            }
+            return joiner.toString();
+        }
+        if (o instanceof java.util.Map) {
+            java.util.StringJoiner joiner = new java.util.StringJoiner(", ", "{", "}");
+            for (java.util.Map.Entry<?, ?> entry : ((java.util.Map<?, ?>) o).entrySet()) {
+                joiner.add(entry.getKey() + "=" + deepToString(entry.getValue()));
+            }
+            return joiner.toString();
+        }
+        return String.valueOf(o);
+    }
+
+`)
+}
+
 func (jt *JavaTemplate) getTypeImport(tp *core.Type, context *core.Context, tps []string) []string {
 	switch tp.Number {
 	case core.Array, core.Map: //only array or map value maybe contains message type
@@ -253,7 +717,7 @@ func (jt *JavaTemplate) getTypeImport(tp *core.Type, context *core.Context, tps
 	case core.Msg:
 		index := strings.LastIndex(tp.Name, ".")
 		if index > -1 { //not same package
-			if msg, ok := context.Messages[tp.Name]; ok {
+			if msg := context.GetMessage(tp.Name); msg != nil {
 				pkg := msg.Options[core.JavaPackage]
 				if pkg != "" {
 					tps = append(tps, "import "+pkg+"."+tp.Name[index+1:]+";\n")
@@ -0,0 +1,43 @@
+package templates_test
+
+import (
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	generator "github.com/weibreeze/breeze-generator"
+)
+
+func TestJavaObjectMethods(t *testing.T) {
+	assert := assert2.New(t)
+	files := map[string]string{
+		"u.breeze": "package test.u;\nenum E(keep_unknown_enum = true) {\n    A = 1;\n}\n" +
+			"message User {\n    int32 id = 1;\n    string password = 2 (sensitive = true);\n    bytes data = 3;\n    float32 score = 4;\n" +
+			"    map<string, array<User>> friends = 5;\n    E e = 6;\n}\n" +
+			"message Secret {\n    string token = 1 (sensitive = true);\n}\n" +
+			"message Blobs {\n    array<bytes> chunks = 1;\n    map<string, array<bytes>> groups = 2 (sensitive = true);\n}\n",
+	}
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{CodeTemplates: "java"})
+	assert.Nil(err)
+	user := code["User.java"]
+	assert.Contains(user, "        User that = (User) o;\n        return this.id == that.id\n"+
+		"                && java.util.Objects.equals(this.password, that.password)\n"+
+		"                && java.util.Arrays.equals(this.data, that.data)\n"+
+		"                && Float.compare(this.score, that.score) == 0\n"+
+		"                && java.util.Objects.equals(this.friends, that.friends)\n"+
+		"                && java.util.Objects.equals(this.e, that.e)\n"+
+		"                && this.eUnrecognizedNumber == that.eUnrecognizedNumber;\n")
+	assert.Contains(user, "        return java.util.Objects.hash(this.id, this.password, java.util.Arrays.hashCode(this.data), this.score, this.friends, this.e, this.eUnrecognizedNumber);\n")
+	assert.Contains(user, "        return \"User{id=\" + this.id + \", password=***, data=\" + java.util.Arrays.toString(this.data) + "+
+		"\", score=\" + this.score + \", friends=\" + this.friends + \", e=\" + this.e + \"}\";\n")
+	assert.Contains(code["Secret.java"], "        return \"Secret{token=***}\";\n")
+	assert.NotContains(user, "deepEquals")
+	// byte arrays in collections are compared by content
+	blobs := code["Blobs.java"]
+	assert.Contains(blobs, "        return deepEquals(this.chunks, that.chunks)\n                && deepEquals(this.groups, that.groups);\n")
+	assert.Contains(blobs, "        return java.util.Objects.hash(deepHashCode(this.chunks), deepHashCode(this.groups));\n")
+	assert.Contains(blobs, "        return \"Blobs{chunks=\" + deepToString(this.chunks) + \", groups=***}\";\n")
+	assert.Contains(blobs, "    private static boolean deepEquals(Object a, Object b) {\n")
+	assert.Contains(blobs, "    private static int deepHashCode(Object o) {\n")
+	assert.Contains(blobs, "    private static String deepToString(Object o) {\n")
+	assert.NotContains(code["E.java"], "hashCode")
+}
@@ -45,6 +45,11 @@ func (lt *LuaTemplate) Name() string {
 	return Lua
 }
 
+//Options : options accepted by lua template
+func (lt *LuaTemplate) Options() []*core.Option {
+	return []*core.Option{withPackageDirOption, keepUnknownEnumOption}
+}
+
 //GenerateCode : generate lua code
 func (lt *LuaTemplate) GenerateCode(schema *core.Schema, context *core.Context) (contents map[string][]byte, err error) {
 	contents = make(map[string][]byte)
@@ -219,6 +224,17 @@ local _M_mt = {__index = _M}
 		buf.WriteString("local " + value.Name + " = " + strconv.Itoa(value.Index) + ";\n")
 	}
 
+	// name lookup and values ordered by number
+	names, numbers, values := "", "", ""
+	for _, value := range enumValue {
+		names += "[" + value.Name + "] = '" + value.Name + "', "
+		numbers += value.Name + " = " + value.Name + ", "
+		values += value.Name + ", "
+	}
+	buf.WriteString("\n_M.names = {" + strings.TrimSuffix(names, ", ") + "}\n")
+	buf.WriteString("_M.numbers = {" + strings.TrimSuffix(numbers, ", ") + "}\n")
+	buf.WriteString("_M.values = {" + strings.TrimSuffix(values, ", ") + "}\n")
+
 	msgName := schema.OrgPackage + "." + message.Name
 	schemaField := "    _m_schema:put_field(brz_field_desc(1, 'enumNumber', 'int32'))\n"
 	if message.Alias != "" {
@@ -253,6 +269,20 @@ function _M.value(self)
     return self.enumNumber
 end
 
+function _M.name(self)
+    return _M.names[self.enumNumber] or tostring(self.enumNumber)
+end
+
+_M_mt.__tostring = _M.name
+
+function _M.from_name(self, name)
+    local number = _M.numbers[name]
+    if number == nil then
+        return nil
+    end
+    return _M:new(number)
+end
+` + lt.generateIsRecognized(message, enumValue) + `
 function _M.get_name(self)
     return self._schema:get_name()
 end
@@ -266,6 +296,18 @@ return _M
 	return withPackageDir(strings.ToLower(message.Name), schema, true) + ".lua", buf.Bytes(), nil
 }
 
+// unknown numbers are kept by enum number, is_recognized checks whether the number is known
+func (lt *LuaTemplate) generateIsRecognized(message *core.Message, enumValue []*core.Field) string {
+	if !keepUnknownEnum(message) {
+		return ""
+	}
+	res := "\nfunction _M.is_recognized(self)\n"
+	for _, value := range enumValue {
+		res += "    if self.enumNumber == " + value.Name + " then\n        return true\n    end\n"
+	}
+	return res + "    return false\nend\n"
+}
+
 func (lt *LuaTemplate) generateService(schema *core.Schema, service *core.Service, context *core.Context) (file string, content []byte, err error) {
 	//TODO implement
 	return "", nil, nil
@@ -40,6 +40,11 @@ func (pt *PHPTemplate) Name() string {
 	return Php
 }
 
+//Options : options accepted by php template
+func (pt *PHPTemplate) Options() []*core.Option {
+	return []*core.Option{withPackageDirOption, keepUnknownEnumOption, withTestsOption}
+}
+
 //GenerateCode : generate php code
 func (pt *PHPTemplate) GenerateCode(schema *core.Schema, context *core.Context) (contents map[string][]byte, err error) {
 	contents = make(map[string][]byte)
@@ -71,9 +76,128 @@ func (pt *PHPTemplate) GenerateCode(schema *core.Schema, context *core.Context)
 			}
 		}
 	}
+	if withTests(schema) {
+		if file, content := pt.generateTest(schema, context); content != nil {
+			contents[file] = content
+		}
+	}
 	return contents, nil
 }
 
+// generateTest generates a PHPUnit test which encodes and decodes messages with pseudo-random values.
+// it returns nil content if schema has no message.
+func (pt *PHPTemplate) generateTest(schema *core.Schema, context *core.Context) (file string, content []byte) {
+	messages := make([]*core.Message, 0, len(schema.Messages))
+	importStr := make([]string, 0, 16)
+	for _, message := range sortMessages(schema) {
+		if !message.IsEnum {
+			messages = append(messages, message)
+			for _, field := range message.Fields {
+				for _, t := range pt.getTypeImport(field.Type, nil) {
+					if !strings.HasPrefix(t, "use Breeze\\") { // only classes of messages are used
+						importStr = append(importStr, t)
+					}
+				}
+			}
+		}
+	}
+	if len(messages) == 0 {
+		return "", nil
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteString("<?php\n")
+	writeGenerateComment(buf, schema.Name)
+	ns := pt.getNamespace(schema.Package)
+	if ns != "" {
+		buf.WriteString("namespace " + ns + ";\n\n")
+	}
+	buf.WriteString("use Breeze\\BreezeReader;\nuse Breeze\\BreezeWriter;\nuse Breeze\\Buffer;\nuse PHPUnit\\Framework\\TestCase;\n")
+	for _, t := range sortUnique(importStr) {
+		buf.WriteString(t)
+	}
+	name := schemaClassName(schema) + "BreezeTest"
+	buf.WriteString("\nclass " + name + " extends TestCase {\n")
+	for _, message := range messages {
+		buf.WriteString("    public function test" + message.Name + "RoundTrip() {\n        mt_srand(1);\n        for ($i = 0; $i < 20; $i++) {\n")
+		buf.WriteString("            $message = self::random" + message.Name + "(0);\n            $buf = new Buffer();\n            BreezeWriter::writeValue($buf, $message);\n")
+		buf.WriteString("            $decoded = BreezeReader::readValue(new Buffer($buf->buffer()), new " + message.Name + "());\n")
+		buf.WriteString("            $this->assertEquals($message, $decoded);\n        }\n    }\n\n")
+	}
+	for _, message := range messages {
+		// collections are not empty, because empty collections may be decoded as null
+		buf.WriteString("    // messages deeper than 2 are not set\n")
+		buf.WriteString("    private static function random" + message.Name + "($depth) {\n        $m = new " + message.Name + "();\n")
+		for _, field := range sortFields(message) {
+			value := "$f" + strconv.Itoa(field.Index)
+			blank := "        "
+			if containsMessage(field.Type, schema, context) {
+				buf.WriteString("        if ($depth < 2) {\n")
+				blank += "    "
+			}
+			pt.randomValue(buf, field.Type, value, blank, 1, schema, context)
+			buf.WriteString(blank + "$m->set" + firstUpper(field.Name) + "(" + value + ");\n")
+			if blank != "        " {
+				buf.WriteString("        }\n")
+			}
+		}
+		buf.WriteString("        return $m;\n    }\n\n")
+	}
+	buf.Truncate(buf.Len() - 1)
+	buf.WriteString("}\n")
+	return withPackageDir(name, schema, true) + ".php", buf.Bytes()
+}
+
+// randomValue writes the statements which set name to a pseudo-random value of type tp.
+// floats are exact in float32, and messages declared in other schemas are empty.
+func (pt *PHPTemplate) randomValue(buf *bytes.Buffer, tp *core.Type, name string, blank string, recursion int, schema *core.Schema, context *core.Context) {
+	recStr := strconv.Itoa(recursion)
+	var value string
+	switch tp.Number {
+	case core.Bool:
+		value = "mt_rand(0, 1) == 1"
+	case core.String: // not numeric, so it is not converted to integer as a key of array
+		value = "'s' . mt_rand()"
+	case core.Byte:
+		value = "mt_rand(0, 127)"
+	case core.Bytes:
+		value = "md5(mt_rand(), true)"
+	case core.Int16:
+		value = "mt_rand(-32768, 32767)"
+	case core.Int32:
+		value = "mt_rand(-2147483648, 2147483647)"
+	case core.Int64:
+		value = "mt_rand(-2147483648, 2147483647) * 4294967296 + mt_rand()"
+	case core.Float32:
+		value = "mt_rand(0, 1 << 20) / 1024"
+	case core.Float64:
+		value = "mt_rand() / mt_getrandmax()"
+	case core.Array:
+		buf.WriteString(blank + name + " = [];\n")
+		buf.WriteString(blank + "for ($i" + recStr + " = mt_rand(1, 3); $i" + recStr + " > 0; $i" + recStr + "--) {\n")
+		pt.randomValue(buf, tp.ValueType, "$v"+recStr, blank+"    ", recursion+1, schema, context)
+		buf.WriteString(blank + "    " + name + "[] = $v" + recStr + ";\n" + blank + "}\n")
+		return
+	case core.Map:
+		buf.WriteString(blank + name + " = [];\n")
+		buf.WriteString(blank + "for ($i" + recStr + " = mt_rand(1, 3); $i" + recStr + " > 0; $i" + recStr + "--) {\n")
+		pt.randomValue(buf, tp.KeyType, "$k"+recStr, blank+"    ", recursion+1, schema, context)
+		pt.randomValue(buf, tp.ValueType, "$v"+recStr, blank+"    ", recursion+1, schema, context)
+		buf.WriteString(blank + "    " + name + "[$k" + recStr + "] = $v" + recStr + ";\n" + blank + "}\n")
+		return
+	case core.Msg:
+		className := tp.Name[strings.LastIndex(tp.Name, ".")+1:]
+		switch {
+		case isEnum(tp, schema, context):
+			value = "new " + className + "(" + className + "::values()[mt_rand(0, count(" + className + "::values()) - 1)])"
+		case schemaMessage(tp, schema) != nil:
+			value = "self::random" + className + "($depth + 1)"
+		default:
+			value = "new " + className + "()"
+		}
+	}
+	buf.WriteString(blank + name + " = " + value + ";\n")
+}
+
 func (pt *PHPTemplate) generateMessage(schema *core.Schema, message *core.Message, context *core.Context) (file string, content []byte, err error) {
 	buf := &bytes.Buffer{}
 	buf.WriteString("<?php\n")
@@ -206,6 +330,31 @@ func (pt *PHPTemplate) generateEnum(schema *core.Schema, message *core.Message,
 	// enum value
 	buf.WriteString("    public function value() {\n        return $this->enumValue;\n    }\n\n")
 
+	// names and numbers
+	buf.WriteString("    private static $names = [")
+	for i, value := range enumValue {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(strconv.Itoa(value.Index) + " => '" + value.Name + "'")
+	}
+	buf.WriteString("];\n\n")
+	buf.WriteString("    // numbers of all values ordered by number\n    public static function values() {\n        return array_keys(self::$names);\n    }\n\n")
+	buf.WriteString("    // name in schema, or the number if it is unknown\n    public function name() {\n")
+	buf.WriteString("        return isset(self::$names[$this->enumValue]) ? self::$names[$this->enumValue] : strval($this->enumValue);\n    }\n\n")
+	buf.WriteString("    public function __toString() {\n        return $this->name();\n    }\n\n")
+	buf.WriteString("    public static function fromName($name) {\n        $number = array_search($name, self::$names, true);\n")
+	buf.WriteString("        if ($number === false) {\n            throw new BreezeException('unknown name of enum " + message.Name + ": ' . $name);\n        }\n")
+	buf.WriteString("        return new " + message.Name + "($number);\n    }\n\n")
+	keepUnknown := keepUnknownEnum(message)
+	if keepUnknown { // unknown numbers are kept, and written unchanged
+		buf.WriteString("    public function isRecognized() {\n        switch ($this->enumValue) {\n")
+		for _, value := range enumValue {
+			buf.WriteString("            case self::" + value.Name + ":\n")
+		}
+		buf.WriteString("                return true;\n        }\n        return false;\n    }\n\n")
+	}
+
 	//initSchema
 	buf.WriteString("    private function initSchema() {\n        self::$_schema = new Schema();\n        self::$_schema->setName('" + schema.OrgPackage + "." + message.Name + "');\n")
 	if message.Alias != "" {
@@ -224,7 +373,11 @@ func (pt *PHPTemplate) generateEnum(schema *core.Schema, message *core.Message,
 	for _, value := range enumValue {
 		buf.WriteString("                        case " + strconv.Itoa(value.Index) + ":\n                            $this->enumValue = self::" + value.Name + ";\n                            break;\n")
 	}
-	buf.WriteString("                        default:\n                            throw new BreezeException('unknown enum number ' . $number);\n                    }\n                    break;\n")
+	if keepUnknown {
+		buf.WriteString("                        default: // keep unknown number, it is written unchanged\n                            $this->enumValue = $number;\n                    }\n                    break;\n")
+	} else {
+		buf.WriteString("                        default:\n                            throw new BreezeException('unknown enum number ' . $number);\n                    }\n                    break;\n")
+	}
 	buf.WriteString("                default: // for compatibility\n                    BreezeReader::readValue($funcBuf);\n            }\n        });\n    }\n\n")
 
 	//message interface methods
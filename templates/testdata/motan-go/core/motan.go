@@ -0,0 +1,217 @@
+// Package core is a stand-in of github.com/weibocom/motan-go/core for the tests of generated go code.
+// It only contains the types and functions used by the generated motan-go adapters and their tests,
+// with the same signatures as motan-go v1.1.0, see the README of testdata/motan-go.
+package core
+
+import (
+	"sync"
+
+	"github.com/weibreeze/breeze-go"
+)
+
+// exception types
+const (
+	FrameworkException = iota
+	ServiceException
+	BizException
+)
+
+type URL struct {
+	Protocol   string
+	Host       string
+	Port       int
+	Path       string
+	Group      string
+	Parameters map[string]string
+}
+
+type Exception struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+	ErrType int    `json:"errtype"`
+}
+
+type Request interface {
+	GetMethod() string
+	GetRequestID() uint64
+	GetArguments() []interface{}
+	GetAttachment(key string) string
+	SetAttachment(key string, value string)
+	ProcessDeserializable(toTypes []interface{}) error
+}
+
+type Response interface {
+	GetRequestID() uint64
+	GetValue() interface{}
+	GetException() *Exception
+	ProcessDeserializable(toType interface{}) error
+}
+
+type Provider interface {
+	SetService(s interface{})
+	GetURL() *URL
+	SetURL(url *URL)
+	IsAvailable() bool
+	Call(request Request) Response
+	Destroy()
+	GetPath() string
+}
+
+type NewProviderFunc func(url *URL) Provider
+
+// ExtensionFactory contains the provider methods of the extension factory of motan-go
+type ExtensionFactory interface {
+	GetProvider(url *URL) Provider
+	RegistExtProvider(name string, newProvider NewProviderFunc)
+}
+
+type AsyncResult struct {
+	StartTime int64
+	Done      chan *AsyncResult
+	Reply     interface{}
+	Error     error
+}
+
+// DeserializableValue is the breeze encoded body of request or response
+type DeserializableValue struct {
+	Body []byte
+}
+
+// Deserialize decodes the value into v
+func (d *DeserializableValue) Deserialize(v interface{}) (interface{}, error) {
+	return breeze.ReadValue(breeze.CreateBuffer(d.Body), v)
+}
+
+// DeserializeMulti decodes the values in order into v
+func (d *DeserializableValue) DeserializeMulti(v []interface{}) ([]interface{}, error) {
+	buf := breeze.CreateBuffer(d.Body)
+	result := make([]interface{}, len(v))
+	for i := range v {
+		value, err := breeze.ReadValue(buf, v[i])
+		if err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+// SerializeMulti encodes the values in order by breeze, the same as breeze serialization of motan-go
+func SerializeMulti(v []interface{}) ([]byte, error) {
+	buf := breeze.NewBuffer(64)
+	for _, value := range v {
+		if err := breeze.WriteValue(buf, value); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+type MotanRequest struct {
+	RequestID  uint64
+	Method     string
+	Arguments  []interface{}
+	Attachment *StringMap
+}
+
+// StringMap is the attachments of request
+type StringMap struct {
+	lock sync.RWMutex
+	m    map[string]string
+}
+
+func NewStringMap(cap int) *StringMap {
+	return &StringMap{m: make(map[string]string, cap)}
+}
+
+func (m *StringMap) Store(key, value string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.m[key] = value
+}
+
+func (m *StringMap) LoadOrEmpty(key string) string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.m[key]
+}
+
+func (m *MotanRequest) GetMethod() string {
+	return m.Method
+}
+
+func (m *MotanRequest) GetRequestID() uint64 {
+	return m.RequestID
+}
+
+func (m *MotanRequest) GetArguments() []interface{} {
+	return m.Arguments
+}
+
+func (m *MotanRequest) GetAttachment(key string) string {
+	if m.Attachment == nil {
+		return ""
+	}
+	return m.Attachment.LoadOrEmpty(key)
+}
+
+func (m *MotanRequest) SetAttachment(key string, value string) {
+	if m.Attachment == nil {
+		m.Attachment = NewStringMap(4)
+	}
+	m.Attachment.Store(key, value)
+}
+
+// ProcessDeserializable decodes the arguments into toTypes, and replaces the arguments with the decoded values
+func (m *MotanRequest) ProcessDeserializable(toTypes []interface{}) error {
+	if len(m.Arguments) != 1 {
+		return nil
+	}
+	d, ok := m.Arguments[0].(*DeserializableValue)
+	if !ok {
+		return nil
+	}
+	values, err := d.DeserializeMulti(toTypes)
+	if err != nil {
+		return err
+	}
+	m.Arguments = values
+	return nil
+}
+
+type MotanResponse struct {
+	RequestID   uint64
+	Value       interface{}
+	Exception   *Exception
+	ProcessTime int64
+}
+
+func (m *MotanResponse) GetRequestID() uint64 {
+	return m.RequestID
+}
+
+func (m *MotanResponse) GetValue() interface{} {
+	return m.Value
+}
+
+func (m *MotanResponse) GetException() *Exception {
+	return m.Exception
+}
+
+// ProcessDeserializable decodes the value into toType
+func (m *MotanResponse) ProcessDeserializable(toType interface{}) error {
+	d, ok := m.Value.(*DeserializableValue)
+	if !ok {
+		return nil
+	}
+	value, err := d.Deserialize(toType)
+	if err != nil {
+		return err
+	}
+	m.Value = value
+	return nil
+}
+
+func BuildExceptionResponse(requestID uint64, e *Exception) *MotanResponse {
+	return &MotanResponse{RequestID: requestID, Exception: e}
+}
@@ -0,0 +1,5 @@
+module github.com/weibocom/motan-go
+
+go 1.16
+
+require github.com/weibreeze/breeze-go v0.1.0
@@ -0,0 +1,86 @@
+// Package motan is a stand-in of github.com/weibocom/motan-go for the tests of generated go code.
+// It only contains the types and functions used by the generated motan-go adapters, with the same signatures as motan-go v1.1.0.
+// Requests of MCClient are encoded by breeze and sent to a provider in process instead of network.
+package motan
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/weibocom/motan-go/core"
+)
+
+var defaultExtFactory = &extFactory{providers: map[string]core.NewProviderFunc{}}
+
+func GetDefaultExtFactory() core.ExtensionFactory {
+	return defaultExtFactory
+}
+
+type extFactory struct {
+	lock      sync.Mutex
+	providers map[string]core.NewProviderFunc
+}
+
+func (f *extFactory) GetProvider(url *core.URL) core.Provider {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if newProvider, ok := f.providers[url.Parameters["provider"]]; ok {
+		return newProvider(url)
+	}
+	return nil
+}
+
+func (f *extFactory) RegistExtProvider(name string, newProvider core.NewProviderFunc) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.providers[name] = newProvider
+}
+
+type MCClient struct {
+	provider  core.Provider
+	requestID uint64
+}
+
+// NewClient creates a client of provider, it only exists in the stand-in.
+// MCClient of motan-go is created by the client context and sends requests to remote providers.
+func NewClient(provider core.Provider) *MCClient {
+	return &MCClient{provider: provider}
+}
+
+func (m *MCClient) Call(method string, args []interface{}, reply interface{}) error {
+	return (<-m.Go(method, args, reply, make(chan *core.AsyncResult, 1)).Done).Error
+}
+
+func (m *MCClient) Go(method string, args []interface{}, reply interface{}, done chan *core.AsyncResult) *core.AsyncResult {
+	if done == nil {
+		done = make(chan *core.AsyncResult, 1)
+	}
+	result := &core.AsyncResult{StartTime: time.Now().UnixNano(), Done: done, Reply: reply}
+	go func() {
+		result.Error = m.call(method, args, reply)
+		done <- result
+	}()
+	return result
+}
+
+func (m *MCClient) call(method string, args []interface{}, reply interface{}) error {
+	body, err := core.SerializeMulti(args)
+	if err != nil {
+		return err
+	}
+	request := &core.MotanRequest{RequestID: atomic.AddUint64(&m.requestID, 1), Method: method,
+		Arguments: []interface{}{&core.DeserializableValue{Body: body}}}
+	response := m.provider.Call(request)
+	if e := response.GetException(); e != nil {
+		return errors.New(e.ErrMsg)
+	}
+	if reply == nil {
+		return nil
+	}
+	if body, err = core.SerializeMulti([]interface{}{response.GetValue()}); err != nil {
+		return err
+	}
+	return (&core.MotanResponse{Value: &core.DeserializableValue{Body: body}}).ProcessDeserializable(reply)
+}
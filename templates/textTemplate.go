@@ -0,0 +1,380 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/weibreeze/breeze-generator/core"
+	"gopkg.in/yaml.v3"
+)
+
+//TextTemplateConfigFile is the optional config file in the directory of a TextTemplate
+const TextTemplateConfigFile = "template.yaml"
+
+//TextTemplateSuffix is the suffix of template files in the directory of a TextTemplate
+const TextTemplateSuffix = ".tmpl"
+
+//file kinds of TextTemplate, a file is generated for each schema, message, enum or service
+const (
+	PerSchema  = "schema"
+	PerMessage = "message"
+	PerEnum    = "enum"
+	PerService = "service"
+)
+
+//TextTemplate : generate code by text/template files in a directory, so a new target language can be added without go code.
+//
+//every `*.tmpl` file starts with a yaml front matter, which declares the output file path (also a template),
+//and whether a file is generated for each schema, message, enum or service:
+//
+//	---
+//	per: message
+//	path: '{{withPackageDir .Schema (print .Message.Name ".java")}}'
+//	---
+//	public class {{.Message.Name}} {
+//	{{- range sortedFields .Message}}
+//	    private {{$.TypeName .Type}} {{.Name}};
+//	{{- end}}
+//	}
+//
+//files named `_*.tmpl` have no front matter, templates defined in them can be used by all template files.
+//the optional template.yaml declares the template name, the accepted options and the type mapping:
+//
+//	name: kotlin
+//	options:
+//	  - name: package
+//	    description: package of generated classes
+//	types:
+//	  int32: Int
+//	  map: 'Map<{{.Key}}, {{.Value}}>'
+//	  array: 'List<{{.Value}}>'
+//	  message: '{{.ShortName}}'
+//
+//a generated file is skipped if its content is blank.
+type TextTemplate struct {
+	name    string
+	source  string // directory of template files, empty if not loaded from a directory
+	digest  string // content hash of template files
+	options []*core.Option
+	types   map[string]*template.Template
+	files   []*textTemplateFile
+}
+
+type textTemplateFile struct {
+	name string // template file name
+	per  string
+	path *template.Template
+	body *template.Template
+}
+
+type textTemplateConfig struct {
+	Name    string            `yaml:"name"`
+	Options []*core.Option    `yaml:"options"`
+	Types   map[string]string `yaml:"types"`
+}
+
+type textTemplateFrontMatter struct {
+	Per  string `yaml:"per"`
+	Path string `yaml:"path"`
+}
+
+//TextTemplateData is the data passed to the templates of a TextTemplate
+type TextTemplateData struct {
+	Schema  *core.Schema
+	Message *core.Message // the message or enum of the file, nil if the file is generated per schema or service
+	Service *core.Service // the service of the file, nil if the file is not generated per service
+	Context *core.Context
+	types   map[string]*template.Template
+}
+
+//LoadTextTemplate loads a TextTemplate from a directory. the template name is the directory name if not set in template.yaml
+func LoadTextTemplate(dir string) (*TextTemplate, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	tt, err := NewTextTemplate(filepath.Base(abs), os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+	tt.source = abs
+	return tt, nil
+}
+
+//NewTextTemplate create a TextTemplate from template files in the root of fsys. name is used if the name is not set in template.yaml
+func NewTextTemplate(name string, fsys fs.FS) (*TextTemplate, error) {
+	tt := &TextTemplate{name: name, options: []*core.Option{withPackageDirOption}, types: make(map[string]*template.Template)}
+	h := sha256.New()
+	content, err := fs.ReadFile(fsys, TextTemplateConfigFile)
+	if err == nil {
+		fmt.Fprintf(h, "%s:%d\n%s\n", TextTemplateConfigFile, len(content), content)
+		config := &textTemplateConfig{}
+		if err = yaml.Unmarshal(content, config); err != nil {
+			return nil, fmt.Errorf("wrong %s: %s", TextTemplateConfigFile, err.Error())
+		}
+		if config.Name != "" {
+			tt.name = config.Name
+		}
+		for _, option := range config.Options {
+			if option.Key == "" {
+				option.Key = option.Name
+			}
+			tt.options = append(tt.options, option)
+		}
+		for kind, text := range config.Types {
+			if tt.types[kind], err = template.New(kind).Funcs(textTemplateFuncs).Parse(text); err != nil {
+				return nil, fmt.Errorf("wrong type mapping of %s: %s", kind, err.Error())
+			}
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	names, err := fs.Glob(fsys, "*"+TextTemplateSuffix)
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[string]string, len(names))
+	for _, name := range names {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		contents[name] = string(content)
+		fmt.Fprintf(h, "%s:%d\n%s\n", name, len(content), content)
+	}
+	tt.digest = hex.EncodeToString(h.Sum(nil))
+	// partials are parsed into every template file
+	partials := make(map[string]string)
+	for _, name := range names {
+		if strings.HasPrefix(name, "_") {
+			partials[name] = contents[name]
+		}
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+		file, err := parseTextTemplateFile(name, contents[name], partials)
+		if err != nil {
+			return nil, err
+		}
+		tt.files = append(tt.files, file)
+	}
+	if len(tt.files) == 0 {
+		return nil, errors.New("no template file in template " + tt.name)
+	}
+	return tt, nil
+}
+
+func parseTextTemplateFile(name string, content string, partials map[string]string) (*textTemplateFile, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return nil, errors.New("no front matter in template file: " + name)
+	}
+	end := strings.Index(content[4:], "\n---\n")
+	if end < 0 {
+		return nil, errors.New("front matter not closed in template file: " + name)
+	}
+	fm := &textTemplateFrontMatter{Per: PerSchema}
+	if err := yaml.Unmarshal([]byte(content[4:4+end+1]), fm); err != nil {
+		return nil, fmt.Errorf("wrong front matter in template file: %s, err:%s", name, err.Error())
+	}
+	if fm.Per != PerSchema && fm.Per != PerMessage && fm.Per != PerEnum && fm.Per != PerService {
+		return nil, fmt.Errorf("wrong `per` in template file: %s, must be one of schema, message, enum and service", name)
+	}
+	if fm.Path == "" {
+		return nil, errors.New("no `path` in template file: " + name)
+	}
+	file := &textTemplateFile{name: name, per: fm.Per}
+	var err error
+	if file.path, err = template.New(name + ":path").Funcs(textTemplateFuncs).Parse(fm.Path); err != nil {
+		return nil, err
+	}
+	file.body = template.New(name).Funcs(textTemplateFuncs)
+	partialNames := make([]string, 0, len(partials))
+	for partial := range partials {
+		partialNames = append(partialNames, partial)
+	}
+	sort.Strings(partialNames)
+	for _, partial := range partialNames {
+		if _, err = file.body.New(partial).Parse(partials[partial]); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = file.body.Parse(content[4+end+5:]); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+//Name : template name
+func (tt *TextTemplate) Name() string {
+	return tt.name
+}
+
+//Options : options declared in template.yaml
+func (tt *TextTemplate) Options() []*core.Option {
+	return tt.options
+}
+
+//CacheKey : the directory and content hash of template files, so the cached code is regenerated when they change
+func (tt *TextTemplate) CacheKey(context *core.Context) string {
+	return tt.source + ":" + tt.digest
+}
+
+//GenerateCode : generate code by template files
+func (tt *TextTemplate) GenerateCode(schema *core.Schema, context *core.Context) (contents map[string][]byte, err error) {
+	contents = make(map[string][]byte)
+	for _, file := range tt.files {
+		data := make([]*TextTemplateData, 0, 16)
+		switch file.per {
+		case PerSchema:
+			data = append(data, &TextTemplateData{Schema: schema, Context: context, types: tt.types})
+		case PerMessage, PerEnum:
+			for _, message := range sortMessages(schema) {
+				if message.IsEnum == (file.per == PerEnum) {
+					data = append(data, &TextTemplateData{Schema: schema, Message: message, Context: context, types: tt.types})
+				}
+			}
+		case PerService:
+			for _, service := range sortServices(schema) {
+				data = append(data, &TextTemplateData{Schema: schema, Service: service, Context: context, types: tt.types})
+			}
+		}
+		for _, d := range data {
+			name, content, err := file.execute(d)
+			if err != nil {
+				return nil, err
+			}
+			if len(bytes.TrimSpace(content)) == 0 {
+				continue
+			}
+			if _, ok := contents[name]; ok {
+				return nil, fmt.Errorf("template file %s generates duplicate file: %s", file.name, name)
+			}
+			contents[name] = content
+		}
+	}
+	return contents, nil
+}
+
+func (f *textTemplateFile) execute(data *TextTemplateData) (string, []byte, error) {
+	buf := &bytes.Buffer{}
+	if err := f.path.Execute(buf, data); err != nil {
+		return "", nil, err
+	}
+	name := path.Clean(filepath.ToSlash(strings.TrimSpace(buf.String())))
+	if name == "." || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", nil, fmt.Errorf("template file %s generates wrong file path: %s", f.name, buf.String())
+	}
+	buf.Reset()
+	if err := f.body.Execute(buf, data); err != nil {
+		return "", nil, err
+	}
+	return filepath.FromSlash(name), buf.Bytes(), nil
+}
+
+//Option : get the option of schema by key
+func (d *TextTemplateData) Option(key string) string {
+	return d.Schema.Options[key]
+}
+
+//IsEnum : whether the type is an enum
+func (d *TextTemplateData) IsEnum(tp *core.Type) bool {
+	return isEnum(tp, d.Schema, d.Context)
+}
+
+//FullName : full name with package of a message type
+func (d *TextTemplateData) FullName(tp *core.Type) string {
+	if strings.Contains(tp.Name, ".") {
+		return tp.Name
+	}
+	return d.Schema.Package + "." + tp.Name
+}
+
+//TypeName : type name in target language according to the type mapping in template.yaml.
+//the type mapping of a kind is a template, which can use .Key, .Value (mapped type names), .Name, .ShortName and .Package.
+//the breeze type string is used if the kind has no mapping.
+func (d *TextTemplateData) TypeName(tp *core.Type) (string, error) {
+	kind := typeKinds[tp.Number]
+	if tp.Number == core.Msg && d.IsEnum(tp) {
+		if _, ok := d.types["enum"]; ok {
+			kind = "enum"
+		}
+	}
+	t := d.types[kind]
+	if t == nil {
+		return tp.TypeString, nil
+	}
+	value := &struct{ Key, Value, Name, ShortName, Package string }{}
+	var err error
+	if tp.KeyType != nil {
+		if value.Key, err = d.TypeName(tp.KeyType); err != nil {
+			return "", err
+		}
+	}
+	if tp.ValueType != nil {
+		if value.Value, err = d.TypeName(tp.ValueType); err != nil {
+			return "", err
+		}
+	}
+	if tp.Number == core.Msg {
+		value.Name = d.FullName(tp)
+		index := strings.LastIndex(value.Name, ".")
+		value.ShortName, value.Package = value.Name[index+1:], value.Name[:index]
+	}
+	buf := &bytes.Buffer{}
+	if err = t.Execute(buf, value); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+var typeKinds = map[int]string{
+	core.Bool:    "bool",
+	core.String:  "string",
+	core.Byte:    "byte",
+	core.Bytes:   "bytes",
+	core.Int16:   "int16",
+	core.Int32:   "int32",
+	core.Int64:   "int64",
+	core.Float32: "float32",
+	core.Float64: "float64",
+	core.Map:     "map",
+	core.Array:   "array",
+	core.Msg:     "message",
+}
+
+var textTemplateFuncs = template.FuncMap{
+	"firstUpper":       firstUpper,
+	"firstLower":       firstLower,
+	"camelCase":        toCamelCase,
+	"lower":            strings.ToLower,
+	"upper":            strings.ToUpper,
+	"replace":          strings.ReplaceAll,
+	"join":             strings.Join,
+	"sortedMessages":   sortMessages,
+	"sortedServices":   sortServices,
+	"sortedMethods":    sortMethods,
+	"sortedFields":     sortFields,
+	"sortedEnumValues": sortEnumValues,
+	"sortedParams":     sortParams,
+	"kind":             func(tp *core.Type) string { return typeKinds[tp.Number] },
+	"packageDir": func(schema *core.Schema) string {
+		return strings.ReplaceAll(schema.Package, ".", "/")
+	},
+	"withPackageDir": func(schema *core.Schema, fileName string) string {
+		return filepath.ToSlash(withPackageDir(fileName, schema, false))
+	},
+}
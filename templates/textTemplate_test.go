@@ -0,0 +1,137 @@
+package templates_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	assert2 "github.com/stretchr/testify/assert"
+	generator "github.com/weibreeze/breeze-generator"
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+func TestTextTemplate(t *testing.T) {
+	assert := assert2.New(t)
+	dir := t.TempDir()
+	templateFiles := map[string]string{
+		"template.yaml": `name: kotlin
+options:
+  - name: package
+    description: package of generated classes
+types:
+  int32: Int
+  string: String
+  map: 'Map<{{.Key}}, {{.Value}}>'
+  array: 'List<{{.Value}}>'
+  message: '{{.ShortName}}'
+  enum: '{{.Package}}.{{.ShortName}}'
+`,
+		"_header.tmpl": `{{define "header"}}// schema: {{.Schema.Name}}
+package {{or (.Option "package") .Schema.Package}}
+{{end}}`,
+		"message.tmpl": `---
+per: message
+path: '{{withPackageDir .Schema (print .Message.Name ".kt")}}'
+---
+{{template "header" .}}
+data class {{.Message.Name}}(
+{{- range sortedFields .Message}}
+    val {{firstLower .Name}}: {{$.TypeName .Type}},
+{{- end}}
+)
+`,
+		"enum.tmpl": `---
+per: enum
+path: '{{.Message.Name}}.kt'
+---
+enum class {{.Message.Name}} { {{range sortedEnumValues .Message}}{{upper .Name}}, {{end}}}
+`,
+		"service.tmpl": `---
+per: service
+path: '{{.Service.Name}}.kt'
+---
+interface {{.Service.Name}} {
+{{- range sortedMethods .Service}}
+    fun {{.Name}}({{range $i, $p := sortedParams .}}{{if $i}}, {{end}}{{$p.Name}}: {{$.TypeName $p.Type}}{{end}})
+{{- end}}
+}
+`,
+		"empty.tmpl": `---
+path: '{{.Schema.Name}}.txt'
+---
+{{if .Schema.Services}}has services{{end}}
+`,
+	}
+	for name, content := range templateFiles {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"a.breeze": `package test.a;
+message A {
+    int32 Id = 1;
+    map<string, array<test.b.B>> bs = 2;
+    test.b.E e = 3;
+}
+service S {
+    get(int32 id, string name)A;
+}
+`,
+		"b.breeze": "package test.b;\nmessage B {\n    string name = 1;\n}\nenum E {\n    x = 1;\n    y = 2;\n}\n",
+	}
+	config := &generator.Config{TextTemplates: []string{dir}, Options: map[string]string{"kotlin.package": "com.weibo", core.WithPackageDir: "true"}}
+	code, _, err := generator.GeneratByFileContent(files, config)
+	assert.Nil(err)
+	assert.Equal(`// schema: a.breeze
+package com.weibo
+
+data class A(
+    val id: Int,
+    val bs: Map<String, List<B>>,
+    val e: test.b.E,
+)
+`, code[filepath.Join("test", "a", "A.kt")])
+	assert.Equal("enum class E { X, Y, }\n", code["E.kt"])
+	assert.Equal("interface S {\n    fun get(id: Int, name: String)\n}\n", code["S.kt"])
+	assert.Equal("has services\n", code["a.breeze.txt"])
+	assert.Equal("", code["b.breeze.txt"])
+	assert.Equal(5, len(code))
+
+	// wrong template file
+	if err := ioutil.WriteFile(filepath.Join(dir, "wrong.tmpl"), []byte("no front matter"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = generator.GeneratByFileContent(files, config)
+	assert.NotNil(err)
+}
+
+func TestTextTemplateCache(t *testing.T) {
+	assert := assert2.New(t)
+	dir := t.TempDir()
+	write := func(text string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, "message.tmpl"), []byte("---\nper: message\npath: '{{.Message.Name}}.txt'\n---\n"+text+" {{.Message.Name}}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("message")
+	files := map[string]string{"a.breeze": "package test.a;\nmessage A {\n    int32 id = 1;\n}\n"}
+	cache, _ := generator.NewCache("")
+	for i := 0; i < 2; i++ {
+		code, _, err := generator.GeneratByFileContent(files, &generator.Config{TextTemplates: []string{dir}, Cache: cache})
+		assert.Nil(err)
+		assert.Equal("message A\n", code["A.txt"])
+	}
+	hits, misses := cache.Stats()
+	assert.Equal(1, hits)
+	assert.Equal(1, misses)
+
+	// template files changed, the cached code is regenerated
+	write("data class")
+	code, _, err := generator.GeneratByFileContent(files, &generator.Config{TextTemplates: []string{dir}, Cache: cache})
+	assert.Nil(err)
+	assert.Equal("data class A\n", code["A.txt"])
+	hits, misses = cache.Stats()
+	assert.Equal(1, hits)
+	assert.Equal(2, misses)
+}
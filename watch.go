@@ -0,0 +1,174 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/weibreeze/breeze-generator/core"
+)
+
+//DefaultWatchInterval is the default polling interval of Watcher
+const DefaultWatchInterval = time.Second
+
+//Watcher polls the schema files in a directory, and regenerates the changed schemas and the schemas referencing their messages.
+//unchanged outputs are not rewritten, and outputs of deleted schemas are removed.
+type Watcher struct {
+	Path     string
+	Config   *Config
+	Interval time.Duration
+	files    map[string]*watchedFile // relative file path -> watched file
+}
+
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+	schema  *core.Schema // last successfully parsed schema
+}
+
+//NewWatcher create a Watcher for the schema directory path. a memory Cache is used if config.Cache is nil
+func NewWatcher(path string, config *Config) (*Watcher, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, errors.New("watch path must be a directory: " + path)
+	}
+	if config == nil {
+		config = &Config{}
+	}
+	if config.WritePath == "" {
+		config.WritePath = path
+	}
+	if config.Cache == nil {
+		config.Cache, _ = NewCache("")
+	}
+	config.WriteFile = true // write to file
+	return &Watcher{Path: path, Config: config, Interval: DefaultWatchInterval, files: make(map[string]*watchedFile)}, nil
+}
+
+//Run generates all schemas, then polls and regenerates until stop is closed
+func (w *Watcher) Run(stop <-chan struct{}) error {
+	if _, err := w.Poll(); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			if _, err := w.Poll(); err != nil {
+				fmt.Printf("[watch] error: %s\n", err)
+			}
+		}
+	}
+}
+
+//Poll checks the schema files once, and regenerates code if any schema file is created, changed or deleted.
+//it returns whether any schema file changed.
+func (w *Watcher) Poll() (bool, error) {
+	context, err := initContext(w.Config)
+	if err != nil {
+		return false, err
+	}
+	fsys := os.DirFS(w.Path)
+	filter := &fileFilter{includes: w.Config.Includes, excludes: w.Config.Excludes}
+	files := findSchemaFilesFS(fsys, ".", context.Parser.FileSuffix(), filter, nil, 0, nil)
+
+	// find changed and removed files
+	changed := make([]string, 0, 16)
+	exists := make(map[string]bool, len(files))
+	for _, name := range files {
+		exists[name] = true
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			continue
+		}
+		if f := w.files[name]; f != nil && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+			continue
+		}
+		f := w.files[name]
+		if f == nil {
+			f = &watchedFile{}
+			w.files[name] = f
+		}
+		f.modTime, f.size = info.ModTime(), info.Size()
+		changed = append(changed, name)
+	}
+	removed := make([]string, 0, 16)
+	for name, f := range w.files {
+		if !exists[name] {
+			removed = append(removed, name)
+			if f.schema != nil {
+				removed[len(removed)-1] = f.schema.Name
+			}
+			delete(w.files, name)
+		}
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		return false, nil
+	}
+	sort.Strings(removed)
+
+	// only parse changed files. if a file can not be parsed, the last parsed schema is used
+	schemas := make([]*core.Schema, len(changed))
+	errs := make([]error, len(changed))
+	runParallel(context.Concurrency, len(changed), func(index int) {
+		content, err := fs.ReadFile(fsys, changed[index])
+		if err == nil {
+			schemas[index], err = buildSchema(path.Base(changed[index]), content, context)
+		}
+		errs[index] = err
+	})
+	changedSchemas := make(map[string]bool, len(changed))
+	for index, name := range changed {
+		if errs[index] != nil {
+			fmt.Printf("[watch] error: %s: %s\n", name, errs[index])
+			continue
+		}
+		w.files[name].schema = schemas[index]
+		changedSchemas[schemas[index].Name] = true
+	}
+	for _, name := range files {
+		if f := w.files[name]; f != nil && f.schema != nil {
+			context.AddSchema(f.schema)
+		}
+	}
+
+	// schemas referencing changed schemas directly or indirectly, or referencing removed schemas are regenerated too
+	impacted := make(map[string]bool, len(changedSchemas))
+	for name := range changedSchemas {
+		impacted[name] = true
+	}
+	deps, missing := dependencyClosure(dependencies(context))
+	for name, dependencies := range deps {
+		for _, dep := range dependencies {
+			if changedSchemas[dep] {
+				impacted[name] = true
+			}
+		}
+	}
+	for name := range missing {
+		if len(removed) > 0 {
+			impacted[name] = true
+		}
+	}
+
+	if err = initOutput(context, w.Config, true); err != nil {
+		return true, err
+	}
+	hits, misses := w.Config.Cache.Stats()
+	err = generateCode(context, w.Config.Cache)
+	newHits, newMisses := w.Config.Cache.Stats()
+	fmt.Printf("[watch] %s changed: [%s], removed: [%s], regenerated: [%s], cache hits: %d, misses: %d\n", time.Now().Format("15:04:05"),
+		strings.Join(changed, ", "), strings.Join(removed, ", "), strings.Join(sortedKeys(impacted), ", "), newHits-hits, newMisses-misses)
+	return true, err
+}
@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	assert2 "github.com/stretchr/testify/assert"
+)
+
+func TestWatcher(t *testing.T) {
+	assert := assert2.New(t)
+	dir := t.TempDir()
+	src, dest := filepath.Join(dir, "src"), filepath.Join(dir, "dest")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"a.breeze": "package test.a;\nmessage A {\n    test.b.B b = 1;\n}\n",
+		"b.breeze": "package test.b;\nmessage B {\n    int32 id = 1;\n}\n",
+		"c.breeze": "package test.c;\nmessage C {\n    int32 id = 1;\n}\n",
+	}
+	modTime := time.Now().Add(-time.Hour)
+	write := func(name string, content string) {
+		if err := ioutil.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime = modTime.Add(time.Second)
+		if err := os.Chtimes(filepath.Join(src, name), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		write(name, content)
+	}
+	watcher, err := NewWatcher(src, &Config{CodeTemplates: "java", WritePath: dest})
+	assert.Nil(err)
+	changed, err := watcher.Poll()
+	assert.Nil(err)
+	assert.True(changed)
+	hits, misses := watcher.Config.Cache.Stats()
+	assert.Equal(0, hits)
+	assert.Equal(3, misses)
+	generated, _ := filepath.Glob(filepath.Join(dest, "java", "*.java"))
+	assert.Equal(3, len(generated))
+
+	changed, err = watcher.Poll()
+	assert.Nil(err)
+	assert.False(changed)
+
+	// the schema referencing the changed schema is regenerated too
+	write("b.breeze", "package test.b;\nmessage B {\n    int32 id = 1;\n    string name = 2;\n}\n")
+	changed, err = watcher.Poll()
+	assert.Nil(err)
+	assert.True(changed)
+	hits, misses = watcher.Config.Cache.Stats()
+	assert.Equal(1, hits)
+	assert.Equal(5, misses)
+	content, err := ioutil.ReadFile(filepath.Join(dest, "java", "B.java"))
+	assert.Nil(err)
+	assert.Contains(string(content), "name")
+
+	// a broken schema keeps its last generated code
+	write("b.breeze", "package test.b;\nmessage B {\n")
+	changed, err = watcher.Poll()
+	assert.True(changed)
+	generated, _ = filepath.Glob(filepath.Join(dest, "java", "*.java"))
+	assert.Equal(3, len(generated))
+
+	// outputs of deleted schema are removed
+	if err := os.Remove(filepath.Join(src, "c.breeze")); err != nil {
+		t.Fatal(err)
+	}
+	changed, err = watcher.Poll()
+	assert.Nil(err)
+	assert.True(changed)
+	generated, _ = filepath.Glob(filepath.Join(dest, "java", "*.java"))
+	assert.Equal(2, len(generated))
+
+	_, err = NewWatcher(filepath.Join(src, "a.breeze"), nil)
+	assert.NotNil(err)
+}